@@ -2,8 +2,12 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	vestexported "github.com/cosmos/cosmos-sdk/x/auth/vesting/exported"
 	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
 	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
 )
@@ -13,15 +17,30 @@ type Account struct {
 	Type         string
 	LiquidAmount sdk.Dec
 	StakedAmount sdk.Dec
-	Vote         govtypes.WeightedVoteOptions
-	Delegations  []Delegation
-	VotePercs    map[govtypes.VoteOption]sdk.Dec
+	// UnbondingAmount is the amount of tokens in the unbonding period.
+	UnbondingAmount sdk.Dec
+	// LockedAmount is the part of LiquidAmount still locked by the vesting
+	// schedule at the snapshot time.
+	LockedAmount sdk.Dec
+	Vesting      *Vesting `json:",omitempty"`
+	// Rewards are the outstanding staking rewards, RewardsAmount is their
+	// amount weighted like the liquid amount.
+	Rewards       sdk.Coins `json:",omitempty"`
+	RewardsAmount sdk.Dec
+	Vote          govtypes.WeightedVoteOptions
+	Delegations   []Delegation
+	VotePercs     map[govtypes.VoteOption]sdk.Dec
 }
 
 type Delegation struct {
 	Amount           sdk.Dec
 	ValidatorAddress string
 	Vote             govtypes.WeightedVoteOptions
+	// ShareTokens is true for the LSM tokenized shares held by the account.
+	// They always follow the validator vote, even if the account voted,
+	// because on chain they are delegated by the tokenize share record module
+	// account, which can't vote.
+	ShareTokens bool `json:",omitempty"`
 }
 
 func (a Account) String() string {
@@ -32,30 +51,86 @@ func (a Account) String() string {
 	return string(bz)
 }
 
+// verifyShareRecords returns an error if a balance holds LSM tokenized shares
+// without their tokenize share record. Without the record, the delegation of
+// the record module account would be counted along with the shares.
+func verifyShareRecords(balancesByAddr map[string]sdk.Coins, shareRecords []stakingtypes.TokenizeShareRecord) error {
+	recordDenoms := make(map[string]bool, len(shareRecords))
+	for _, r := range shareRecords {
+		recordDenoms[r.GetShareTokenDenom()] = true
+	}
+	for addr, balance := range balancesByAddr {
+		for _, c := range balance {
+			if _, ok := shareDenomValidator(c.Denom); ok && !recordDenoms[c.Denom] {
+				return fmt.Errorf("%s holds tokenized shares %s without tokenize share record, "+
+					"tokenize_share_records.json is required", addr, c.Denom)
+			}
+		}
+	}
+	return nil
+}
+
 // getAccounts returns the list of all account with their vote and
 // power, from direct or indirect votes.
+//
+// LSM tokenized shares are counted as delegations of their holders, instead
+// of delegations of the tokenize share record module accounts.
+//
+// The vesting schedules are evaluated at snapshotTime.
+//
+// The accounts are sorted by address, and their delegations by validator
+// address, so the output is the same across runs.
 func getAccounts(
 	delegsByAddr map[string][]stakingtypes.Delegation,
 	votesByAddr map[string]govtypes.WeightedVoteOptions,
 	valsByAddr map[string]govtypes.ValidatorGovInfo,
-	balancesByAddr map[string]sdk.Coin,
+	balancesByAddr map[string]sdk.Coins,
+	denomWeights map[string]sdk.Dec,
 	accountTypesPerAddr map[string]string,
+	shareRecords []stakingtypes.TokenizeShareRecord,
+	unbondingsByAddr map[string]sdk.Int,
+	vestingAccsByAddr map[string]vestexported.VestingAccount,
+	snapshotTime time.Time,
+	rewardsByAddr map[string]sdk.Coins,
 ) []Account {
+	recordAddrs := make(map[string]bool, len(shareRecords))
+	for _, r := range shareRecords {
+		recordAddrs[r.GetModuleAddress().String()] = true
+	}
 	accountsByAddr := make(map[string]Account, len(delegsByAddr))
-	// Feed delegations
-	for addr, delegs := range delegsByAddr {
+	// getAccount returns the account of addr, or a new one if it's not yet
+	// known. It returns false if the account must be ignored.
+	getAccount := func(addr string) (Account, bool) {
+		if acc, ok := accountsByAddr[addr]; ok {
+			return acc, true
+		}
 		accType := accountTypesPerAddr[addr]
 		if accType == "/cosmos.auth.v1beta1.ModuleAccount" ||
 			accType == "/ibc.applications.interchain_accounts.v1.InterchainAccount" {
 			// Ignore ModuleAccount & InterchainAccount
+			return Account{}, false
+		}
+		return Account{
+			Address:         addr,
+			Type:            accType,
+			LiquidAmount:    sdk.ZeroDec(),
+			StakedAmount:    sdk.ZeroDec(),
+			UnbondingAmount: sdk.ZeroDec(),
+			LockedAmount:    sdk.ZeroDec(),
+			RewardsAmount:   sdk.ZeroDec(),
+			Vote:            votesByAddr[addr],
+		}, true
+	}
+	// Feed delegations
+	for addr, delegs := range delegsByAddr {
+		if recordAddrs[addr] {
+			// Ignore tokenize share record, its delegation is owned by the holders
+			// of the share tokens.
 			continue
 		}
-		account := Account{
-			Address:      addr,
-			Type:         accType,
-			LiquidAmount: sdk.ZeroDec(),
-			StakedAmount: sdk.ZeroDec(),
-			Vote:         votesByAddr[addr],
+		account, ok := getAccount(addr)
+		if !ok {
+			continue
 		}
 		for _, deleg := range delegs {
 			// Find validator
@@ -80,29 +155,77 @@ func getAccounts(
 	}
 	// Feed balances
 	for addr, balance := range balancesByAddr {
-		acc, ok := accountsByAddr[addr]
-		if ok {
-			acc.LiquidAmount = balance.Amount.ToDec()
-			accountsByAddr[addr] = acc
-		} else {
-			accType := accountTypesPerAddr[addr]
-			if accType == "/cosmos.auth.v1beta1.ModuleAccount" ||
-				accType == "/ibc.applications.interchain_accounts.v1.InterchainAccount" {
-				// Ignore ModuleAccount & InterchainAccount
+		acc, ok := getAccount(addr)
+		if !ok {
+			continue
+		}
+		acc.LiquidAmount = liquidAmount(balance, denomWeights)
+		// Tokenized shares are staked to their validator
+		for _, c := range balance {
+			valAddr, ok := shareDenomValidator(c.Denom)
+			if !ok {
 				continue
 			}
-			accountsByAddr[addr] = Account{
-				Address:      addr,
-				Type:         accType,
-				LiquidAmount: balance.Amount.ToDec(),
-				StakedAmount: sdk.ZeroDec(),
+			val, ok := valsByAddr[valAddr]
+			if !ok {
+				// Validator isn't in active set or jailed, ignore
+				continue
 			}
+			// Share tokens map 1:1 with shares
+			delegVotingPower := c.Amount.ToDec().MulInt(val.BondedTokens).Quo(val.DelegatorShares)
+			acc.StakedAmount = acc.StakedAmount.Add(delegVotingPower)
+			acc.Delegations = append(acc.Delegations, Delegation{
+				ValidatorAddress: val.Address.String(),
+				Amount:           delegVotingPower,
+				Vote:             val.Vote,
+				ShareTokens:      true,
+			})
+		}
+		accountsByAddr[addr] = acc
+	}
+	// Feed unbondings
+	for addr, amount := range unbondingsByAddr {
+		acc, ok := getAccount(addr)
+		if !ok {
+			continue
+		}
+		// Unbonding tokens don't have voting power, whatever the validator
+		acc.UnbondingAmount = amount.ToDec()
+		accountsByAddr[addr] = acc
+	}
+	// Feed rewards
+	for addr, rewards := range rewardsByAddr {
+		acc, ok := getAccount(addr)
+		if !ok {
+			continue
+		}
+		acc.Rewards = rewards
+		acc.RewardsAmount = liquidAmount(rewards, denomWeights)
+		accountsByAddr[addr] = acc
+	}
+	// Feed vesting schedules
+	for addr, vacc := range vestingAccsByAddr {
+		acc, ok := accountsByAddr[addr]
+		if !ok {
+			// Nothing left in the account
+			continue
 		}
+		acc.Vesting = newVesting(vacc, snapshotTime)
+		acc.LockedAmount = liquidAmount(acc.Vesting.LockedCoins, denomWeights)
+		accountsByAddr[addr] = acc
 	}
 	// Map to slice
 	var accounts []Account
 	for _, a := range accountsByAddr {
+		// Stable to keep the delegation before the share tokens of the same
+		// validator.
+		sort.SliceStable(a.Delegations, func(i, j int) bool {
+			return a.Delegations[i].ValidatorAddress < a.Delegations[j].ValidatorAddress
+		})
 		accounts = append(accounts, a)
 	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Address < accounts[j].Address
+	})
 	return accounts
 }
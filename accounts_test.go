@@ -2,8 +2,10 @@ package main
 
 import (
 	"encoding/json"
+	"slices"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -19,7 +21,12 @@ func TestGetAccounts(t *testing.T) {
 		accAddrs = createAccountAddrs(2)
 		accAddr1 = accAddrs[0].String()
 		accAddr2 = accAddrs[1].String()
-		valAddrs = createValidatorAddrs(2)
+		valAddrs = func() []sdk.ValAddress {
+			addrs := createValidatorAddrs(2)
+			// sort addresses to know the order of the delegations
+			sort.Slice(addrs, func(i, j int) bool { return addrs[i].String() < addrs[j].String() })
+			return addrs
+		}()
 		// Validator1
 		valAddr1       = valAddrs[0]
 		valAddr1Str    = valAddr1.String()
@@ -60,12 +67,13 @@ func TestGetAccounts(t *testing.T) {
 			Weight: sdk.NewDec(1),
 		}}
 		// Some initial balances
-		balancesByAddr = map[string]sdk.Coin{
-			accAddr1:       sdk.NewInt64Coin("uatom", 100),
-			accAddr2:       sdk.NewInt64Coin("uatom", 200),
-			valAccAddr1Str: sdk.NewInt64Coin("uatom", 300),
-			valAccAddr2Str: sdk.NewInt64Coin("uatom", 400),
+		balancesByAddr = map[string]sdk.Coins{
+			accAddr1:       sdk.NewCoins(sdk.NewInt64Coin("uatom", 100)),
+			accAddr2:       sdk.NewCoins(sdk.NewInt64Coin("uatom", 200)),
+			valAccAddr1Str: sdk.NewCoins(sdk.NewInt64Coin("uatom", 300)),
+			valAccAddr2Str: sdk.NewCoins(sdk.NewInt64Coin("uatom", 400)),
 		}
+		denomWeights = map[string]sdk.Dec{"uatom": sdk.OneDec()}
 		// Account types
 		accountTypesByAddr = map[string]string{
 			accAddr1:       "accAddr1Type",
@@ -282,6 +290,8 @@ func TestGetAccounts(t *testing.T) {
 					Type:         "valAccAddr1Type",
 					LiquidAmount: sdk.NewDec(300),
 					StakedAmount: sdk.ZeroDec(),
+					// the vote is kept even without stake
+					Vote: voteNo,
 				},
 				{
 					Address:      valAccAddr2Str,
@@ -575,6 +585,7 @@ func TestGetAccounts(t *testing.T) {
 					Type:         "valAccAddr2Type",
 					LiquidAmount: sdk.NewDec(400),
 					StakedAmount: sdk.ZeroDec(),
+					Vote:         voteYes,
 				},
 			},
 		},
@@ -584,14 +595,23 @@ func TestGetAccounts(t *testing.T) {
 			assert := assert.New(t)
 			require := require.New(t)
 
-			accounts := getAccounts(tt.delegsByAddr, tt.votesByAddr, tt.valsByAddr, balancesByAddr, accountTypesByAddr)
+			accounts := getAccounts(tt.delegsByAddr, tt.votesByAddr, tt.valsByAddr, balancesByAddr, denomWeights, accountTypesByAddr, nil, nil, nil, time.Time{}, nil)
 
-			// order is not determistic, sort to have it
-			sort.Slice(accounts, func(i, j int) bool {
-				return accounts[i].Address > accounts[j].Address
-			})
+			for i := range tt.expectedAccounts {
+				// no unbondings, vestings nor rewards in these tests
+				if tt.expectedAccounts[i].UnbondingAmount.IsNil() {
+					tt.expectedAccounts[i].UnbondingAmount = sdk.ZeroDec()
+				}
+				if tt.expectedAccounts[i].LockedAmount.IsNil() {
+					tt.expectedAccounts[i].LockedAmount = sdk.ZeroDec()
+				}
+				if tt.expectedAccounts[i].RewardsAmount.IsNil() {
+					tt.expectedAccounts[i].RewardsAmount = sdk.ZeroDec()
+				}
+			}
+			// accounts are sorted by address
 			sort.Slice(tt.expectedAccounts, func(i, j int) bool {
-				return tt.expectedAccounts[i].Address > tt.expectedAccounts[j].Address
+				return tt.expectedAccounts[i].Address < tt.expectedAccounts[j].Address
 			})
 			exBz, err := json.Marshal(tt.expectedAccounts)
 			require.NoError(err)
@@ -603,6 +623,149 @@ func TestGetAccounts(t *testing.T) {
 	}
 }
 
+func TestGetAccountsTokenizedShares(t *testing.T) {
+	var (
+		require  = require.New(t)
+		assert   = assert.New(t)
+		accAddr1 = createAccountAddrs(1)[0].String()
+		valAddr  = createValidatorAddrs(1)[0]
+		record   = stakingtypes.TokenizeShareRecord{
+			Id:            1,
+			Owner:         accAddr1,
+			ModuleAccount: "tokenizeshare_1",
+			Validator:     valAddr.String(),
+		}
+		recordAddr = record.GetModuleAddress().String()
+		voteNo     = govtypes.WeightedVoteOptions{{
+			Option: govtypes.OptionNo,
+			Weight: sdk.NewDec(1),
+		}}
+		valsByAddr = map[string]govtypes.ValidatorGovInfo{
+			valAddr.String(): {
+				Address:             valAddr,
+				BondedTokens:        sdk.NewInt(1000),
+				DelegatorShares:     sdk.NewDec(2000),
+				DelegatorDeductions: sdk.ZeroDec(),
+				Vote:                voteNo,
+			},
+		}
+		delegsByAddr = map[string][]stakingtypes.Delegation{
+			recordAddr: {{
+				DelegatorAddress: recordAddr,
+				ValidatorAddress: valAddr.String(),
+				Shares:           sdk.NewDec(600),
+			}},
+		}
+		balancesByAddr = map[string]sdk.Coins{
+			accAddr1: sdk.NewCoins(
+				sdk.NewInt64Coin("uatom", 100),
+				sdk.NewInt64Coin(record.GetShareTokenDenom(), 600),
+			),
+		}
+		denomWeights = map[string]sdk.Dec{"uatom": sdk.OneDec()}
+	)
+
+	accounts := getAccounts(delegsByAddr, nil, valsByAddr, balancesByAddr, denomWeights, nil,
+		[]stakingtypes.TokenizeShareRecord{record}, nil, nil, time.Time{}, nil)
+
+	exBz, err := json.Marshal([]Account{{
+		Address:      accAddr1,
+		LiquidAmount: sdk.NewDec(100),
+		// 600 shares * 1000 tokens / 2000 shares
+		StakedAmount:    sdk.NewDec(300),
+		UnbondingAmount: sdk.ZeroDec(),
+		LockedAmount:    sdk.ZeroDec(),
+		RewardsAmount:   sdk.ZeroDec(),
+		Delegations: []Delegation{{
+			ValidatorAddress: valAddr.String(),
+			Amount:           sdk.NewDec(300),
+			Vote:             voteNo,
+			ShareTokens:      true,
+		}},
+	}})
+	require.NoError(err)
+	bz, err := json.Marshal(accounts)
+	require.NoError(err)
+	// The record module account is not part of the accounts
+	assert.JSONEq(string(exBz), string(bz))
+	require.NoError(verifyShareRecords(balancesByAddr, []stakingtypes.TokenizeShareRecord{record}))
+	// Without the records, the shares would be counted twice
+	err = verifyShareRecords(balancesByAddr, nil)
+	require.EqualError(err, accAddr1+" holds tokenized shares "+record.GetShareTokenDenom()+
+		" without tokenize share record, tokenize_share_records.json is required")
+}
+
+func TestGetAccountsTokenizedSharesVotes(t *testing.T) {
+	var (
+		require    = require.New(t)
+		assert     = assert.New(t)
+		accAddrs   = createAccountAddrs(2)
+		sharesOnly = accAddrs[0].String()
+		mixed      = accAddrs[1].String()
+		valAddr    = createValidatorAddrs(1)[0]
+		record     = stakingtypes.TokenizeShareRecord{
+			Id:            1,
+			Owner:         sharesOnly,
+			ModuleAccount: "tokenizeshare_1",
+			Validator:     valAddr.String(),
+		}
+		recordAddr = record.GetModuleAddress().String()
+		voteYes    = govtypes.WeightedVoteOptions{{Option: govtypes.OptionYes, Weight: sdk.OneDec()}}
+		voteNo     = govtypes.WeightedVoteOptions{{Option: govtypes.OptionNo, Weight: sdk.OneDec()}}
+		valsByAddr = map[string]govtypes.ValidatorGovInfo{
+			valAddr.String(): {
+				Address:             valAddr,
+				BondedTokens:        sdk.NewInt(1000),
+				DelegatorShares:     sdk.NewDec(1000),
+				DelegatorDeductions: sdk.ZeroDec(),
+				Vote:                voteNo,
+			},
+		}
+		delegsByAddr = map[string][]stakingtypes.Delegation{
+			recordAddr: {stakingtypes.NewDelegation(record.GetModuleAddress(), valAddr, sdk.NewDec(400))},
+			mixed:      {stakingtypes.NewDelegation(accAddrs[1], valAddr, sdk.NewDec(100))},
+		}
+		balancesByAddr = map[string]sdk.Coins{
+			sharesOnly: sdk.NewCoins(sdk.NewInt64Coin(record.GetShareTokenDenom(), 100)),
+			mixed:      sdk.NewCoins(sdk.NewInt64Coin(record.GetShareTokenDenom(), 300)),
+		}
+		// Both holders voted yes, while their validator voted no
+		votesByAddr = map[string]govtypes.WeightedVoteOptions{
+			sharesOnly: voteYes,
+			mixed:      voteYes,
+		}
+	)
+
+	accounts := getAccounts(delegsByAddr, votesByAddr, valsByAddr, balancesByAddr, nil, nil,
+		[]stakingtypes.TokenizeShareRecord{record}, nil, nil, time.Time{}, nil)
+
+	require.Len(accounts, 2)
+	find := func(addr string) Account {
+		return accounts[slices.IndexFunc(accounts, func(a Account) bool { return a.Address == addr })]
+	}
+	// The vote is set even if the stake is only share tokens
+	acc := find(sharesOnly)
+	assert.Equal(voteYes, acc.Vote)
+	require.Len(acc.Delegations, 1)
+	assert.True(acc.Delegations[0].ShareTokens)
+	acc = find(mixed)
+	assert.Equal(voteYes, acc.Vote)
+	require.Len(acc.Delegations, 2)
+	assert.False(acc.Delegations[0].ShareTokens)
+	assert.True(acc.Delegations[1].ShareTokens)
+
+	votePercs(accounts)
+
+	// The share tokens follow the validator vote, not the holder vote
+	acc = find(sharesOnly)
+	assert.Equal(sdk.OneDec().String(), acc.VotePercs[govtypes.OptionNo].String())
+	assert.True(acc.VotePercs[govtypes.OptionYes].IsZero())
+	// 100 delegated tokens vote yes, 300 share tokens vote no
+	acc = find(mixed)
+	assert.Equal(sdk.NewDecWithPrec(25, 2).String(), acc.VotePercs[govtypes.OptionYes].String())
+	assert.Equal(sdk.NewDecWithPrec(75, 2).String(), acc.VotePercs[govtypes.OptionNo].String())
+}
+
 func createAccountAddrs(accNum int) []sdk.AccAddress {
 	addrs := make([]sdk.AccAddress, accNum)
 	for i := 0; i < accNum; i++ {
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	h "github.com/dustin/go-humanize"
+	"github.com/olekukonko/tablewriter"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+// parseResizedValidatorsByAddr returns the validators of an active set of
+// maxValidators validators, computed from all the validators of
+// validators.json.
+func parseResizedValidatorsByAddr(
+	src dataSource, votesByAddr map[string]govtypes.WeightedVoteOptions, maxValidators uint32,
+) (map[string]govtypes.ValidatorGovInfo, error) {
+	if !src.has("validators.json") {
+		return nil, fmt.Errorf("resizing the active set requires validators.json")
+	}
+	vals, err := parseValidators(src, "validators.json")
+	if err != nil {
+		return nil, err
+	}
+	return newValidatorsByAddr(resizedActiveValidators(vals, maxValidators), votesByAddr), nil
+}
+
+// resizedActiveValidators returns the active set if it had maxValidators
+// validators. Unlike activeValidators, the validators outside of the current
+// active set are candidates, so they are returned as bonded. Jailed
+// validators and validators without consensus power are excluded, like
+// staking.Keeper.ApplyAndReturnValidatorSetUpdates does.
+func resizedActiveValidators(vals []stakingtypes.Validator, maxValidators uint32) []stakingtypes.Validator {
+	var candidates []stakingtypes.Validator
+	for _, val := range vals {
+		if val.IsJailed() || val.PotentialConsensusPower(sdk.DefaultPowerReduction) == 0 {
+			continue
+		}
+		val.Status = stakingtypes.Bonded
+		candidates = append(candidates, val)
+	}
+	return topValidatorsByPower(candidates, maxValidators)
+}
+
+// totalBondedTokens returns the sum of the bonded tokens of the validators.
+func totalBondedTokens(valsByAddr map[string]govtypes.ValidatorGovInfo) sdk.Int {
+	total := sdk.ZeroInt()
+	for _, val := range valsByAddr {
+		total = total.Add(val.BondedTokens)
+	}
+	return total
+}
+
+// activeSetChange reports the validators which leave and join the active set
+// when it is resized.
+type activeSetChange struct {
+	Left         []string
+	LeftTokens   sdk.Int
+	Joined       []string
+	JoinedTokens sdk.Int
+	// LeftDelegators is the number of delegators of the validators which left
+	// the active set, whose delegations have no voting power anymore.
+	LeftDelegators int
+	// LeftVotersStake is the stake of the delegators who voted, delegated to
+	// the validators which left the active set.
+	LeftVotersStake sdk.Dec
+}
+
+func newActiveSetChange(
+	valsByAddr, resizedValsByAddr map[string]govtypes.ValidatorGovInfo,
+	votesByAddr map[string]govtypes.WeightedVoteOptions, delegsByAddr map[string][]stakingtypes.Delegation,
+) activeSetChange {
+	c := activeSetChange{
+		LeftTokens:      sdk.ZeroInt(),
+		JoinedTokens:    sdk.ZeroInt(),
+		LeftVotersStake: sdk.ZeroDec(),
+	}
+	for valAddr, val := range valsByAddr {
+		if _, ok := resizedValsByAddr[valAddr]; !ok {
+			c.Left = append(c.Left, valAddr)
+			c.LeftTokens = c.LeftTokens.Add(val.BondedTokens)
+		}
+	}
+	for valAddr, val := range resizedValsByAddr {
+		if _, ok := valsByAddr[valAddr]; !ok {
+			c.Joined = append(c.Joined, valAddr)
+			c.JoinedTokens = c.JoinedTokens.Add(val.BondedTokens)
+		}
+	}
+	sort.Strings(c.Left)
+	sort.Strings(c.Joined)
+	for addr, delegs := range delegsByAddr {
+		_, voted := votesByAddr[addr]
+		atLeft := false
+		for _, deleg := range delegs {
+			val, ok := valsByAddr[deleg.ValidatorAddress]
+			if !ok {
+				continue
+			}
+			if _, ok := resizedValsByAddr[deleg.ValidatorAddress]; ok {
+				continue
+			}
+			atLeft = true
+			if voted {
+				votingPower := deleg.GetShares().MulInt(val.BondedTokens).Quo(val.DelegatorShares)
+				c.LeftVotersStake = c.LeftVotersStake.Add(votingPower)
+			}
+		}
+		if atLeft {
+			c.LeftDelegators++
+		}
+	}
+	return c
+}
+
+func printActiveSetChange(w io.Writer, c activeSetChange) {
+	fmt.Fprintln(w, "--- ACTIVE SET CHANGE ---")
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"", "Value"})
+	table.SetAutoWrapText(false)
+	table.AppendBulk([][]string{
+		{"Validators leaving", h.Comma(int64(len(c.Left)))},
+		{"Tokens leaving", human(c.LeftTokens)},
+		{"Delegators of leaving validators", h.Comma(int64(c.LeftDelegators))},
+		{"Stake of voters at leaving validators", human(c.LeftVotersStake.TruncateInt())},
+		{"Validators joining", h.Comma(int64(len(c.Joined)))},
+		{"Tokens joining", human(c.JoinedTokens)},
+	})
+	table.Render()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+func TestResizedActiveValidators(t *testing.T) {
+	var (
+		valAddrs = createValidatorAddrs(4)
+		newVal   = func(addr sdk.ValAddress, status stakingtypes.BondStatus, jailed bool, tokens int64) stakingtypes.Validator {
+			return stakingtypes.Validator{
+				OperatorAddress: addr.String(),
+				Status:          status,
+				Jailed:          jailed,
+				Tokens:          sdk.NewInt(tokens),
+				DelegatorShares: sdk.NewDec(tokens),
+			}
+		}
+		vals = []stakingtypes.Validator{
+			newVal(valAddrs[0], stakingtypes.Bonded, false, 1_000_000),
+			newVal(valAddrs[1], stakingtypes.Unbonded, false, 3_000_000),
+			newVal(valAddrs[2], stakingtypes.Bonded, true, 2_000_000),
+			// no consensus power
+			newVal(valAddrs[3], stakingtypes.Unbonding, false, 999_999),
+		}
+	)
+
+	resized := resizedActiveValidators(vals, 3)
+
+	require.Len(t, resized, 2)
+	// unbonded validator joins the active set, jailed one doesn't
+	assert.Equal(t, valAddrs[1].String(), resized[0].OperatorAddress)
+	assert.Equal(t, valAddrs[0].String(), resized[1].OperatorAddress)
+	assert.True(t, resized[0].IsBonded())
+	// vals is left untouched
+	assert.Equal(t, stakingtypes.Unbonded, vals[1].Status)
+
+	resized = resizedActiveValidators(vals, 1)
+
+	require.Len(t, resized, 1)
+	assert.Equal(t, valAddrs[1].String(), resized[0].OperatorAddress)
+}
+
+func TestNewActiveSetChange(t *testing.T) {
+	var (
+		accAddrs = createAccountAddrs(3)
+		valAddrs = createValidatorAddrs(3)
+		voteYes  = govtypes.WeightedVoteOptions{{Option: govtypes.OptionYes, Weight: sdk.OneDec()}}
+		newVal   = func(addr sdk.ValAddress, tokens int64) govtypes.ValidatorGovInfo {
+			return govtypes.NewValidatorGovInfo(addr, sdk.NewInt(tokens), sdk.NewDec(tokens), sdk.ZeroDec(), nil)
+		}
+		valsByAddr = map[string]govtypes.ValidatorGovInfo{
+			valAddrs[0].String(): newVal(valAddrs[0], 1000),
+			valAddrs[1].String(): newVal(valAddrs[1], 500),
+		}
+		resizedValsByAddr = map[string]govtypes.ValidatorGovInfo{
+			valAddrs[0].String(): newVal(valAddrs[0], 1000),
+			valAddrs[2].String(): newVal(valAddrs[2], 800),
+		}
+		delegsByAddr = map[string][]stakingtypes.Delegation{
+			accAddrs[0].String(): {stakingtypes.NewDelegation(accAddrs[0], valAddrs[1], sdk.NewDec(200))},
+			accAddrs[1].String(): {stakingtypes.NewDelegation(accAddrs[1], valAddrs[1], sdk.NewDec(300))},
+			accAddrs[2].String(): {stakingtypes.NewDelegation(accAddrs[2], valAddrs[0], sdk.NewDec(1000))},
+		}
+		votesByAddr = map[string]govtypes.WeightedVoteOptions{
+			accAddrs[0].String(): voteYes,
+			accAddrs[2].String(): voteYes,
+		}
+	)
+
+	c := newActiveSetChange(valsByAddr, resizedValsByAddr, votesByAddr, delegsByAddr)
+
+	assert.Equal(t, []string{valAddrs[1].String()}, c.Left)
+	assert.Equal(t, "500", c.LeftTokens.String())
+	assert.Equal(t, []string{valAddrs[2].String()}, c.Joined)
+	assert.Equal(t, "800", c.JoinedTokens.String())
+	assert.Equal(t, 2, c.LeftDelegators)
+	assert.Equal(t, sdk.NewDec(200).String(), c.LeftVotersStake.String())
+	assert.Equal(t, "1800", totalBondedTokens(resizedValsByAddr).String())
+}
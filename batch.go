@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	h "github.com/dustin/go-humanize"
+	"github.com/olekukonko/tablewriter"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+)
+
+// parseProposalIDs parses a comma separated list of proposal ids and ranges of
+// proposal ids, like "848,850-852".
+func parseProposalIDs(s string) ([]uint64, error) {
+	var (
+		propIDs []uint64
+		seen    = make(map[uint64]bool)
+	)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		fromStr, toStr, isRange := strings.Cut(entry, "-")
+		if !isRange {
+			toStr = fromStr
+		}
+		from, err := strconv.ParseUint(fromStr, 10, 64)
+		if err != nil || from == 0 {
+			return nil, fmt.Errorf("invalid proposal id in %q", entry)
+		}
+		to, err := strconv.ParseUint(toStr, 10, 64)
+		if err != nil || to < from {
+			return nil, fmt.Errorf("invalid proposal range %q", entry)
+		}
+		for id := from; id <= to; id++ {
+			if seen[id] {
+				return nil, fmt.Errorf("duplicate proposal id %d", id)
+			}
+			seen[id] = true
+			propIDs = append(propIDs, id)
+		}
+	}
+	return propIDs, nil
+}
+
+// propReport is the tally of a proposal of a batch, compared to the prop final
+// tally.
+type propReport struct {
+	ProposalID uint64 `json:"proposal_id"`
+	Status     string `json:"status"`
+	Votes      int    `json:"votes"`
+	// Rows are the computed, from prop and diff rows of the tally results.
+	Rows    []tallyRow   `json:"rows"`
+	Outcome tallyOutcome `json:"outcome"`
+	// VotesPruned is true for the proposals tallied before the pre-tally
+	// export, whose votes were removed from the state.
+	VotesPruned bool `json:"votes_pruned"`
+	// Mismatches are the vote options that differ from the prop final tally
+	// by more than the tolerance. Proposals still in voting period are not
+	// verified, since they have no final tally yet, nor the proposals whose
+	// votes were pruned.
+	Mismatches []string `json:"mismatches"`
+}
+
+func (r propReport) pending() bool {
+	return r.Status == govtypes.StatusVotingPeriod.String()
+}
+
+// batchTally tallies the proposals of propIDs read from the exports of src,
+// with rule. The validators and delegations are parsed once for all the
+// proposals.
+func batchTally(src dataSource, propIDs []uint64, rule tallyRule, tolerance sdk.Int) ([]propReport, error) {
+	votesByProp, overrides, err := parseVotesByProp(src, propIDs)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("%d proposals, %d overridden votes\n", len(propIDs), len(overrides))
+	props, err := parseProps(src, propIDs)
+	if err != nil {
+		return nil, err
+	}
+	valsByAddr, err := parseValidatorsByAddr(src, nil)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("%d validators\n", len(valsByAddr))
+	delegsByAddr, err := parseDelegationsByAddr(src)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("%s delegators\n", h.Comma(int64(len(delegsByAddr))))
+	totalBonded, err := parseTotalBondedTokens(src)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]propReport, 0, len(propIDs))
+	for _, propID := range propIDs {
+		var (
+			votesByAddr = votesByProp[propID]
+			prop        = props[propID]
+			res         = rule.tally(votesByAddr, withValidatorVotes(valsByAddr, votesByAddr), delegsByAddr)
+		)
+		report := propReport{
+			ProposalID: propID,
+			Status:     prop.Status.String(),
+			Votes:      len(votesByAddr),
+			Rows:       newTallyRows(res, prop),
+			Outcome:    rule.outcome(res, totalBonded),
+		}
+		// Votes are removed from the state once the proposal is tallied
+		report.VotesPruned = report.Votes == 0 && !report.pending()
+		if !report.pending() && !report.VotesPruned {
+			for _, m := range verifyTally(res, prop, tolerance) {
+				report.Mismatches = append(report.Mismatches, m.String())
+			}
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func printPropReports(reports []propReport) {
+	fmt.Println("--- PROPOSALS ---")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Prop", "Status", "Votes", "Yes", "No", "NoWithVeto", "Abstain", "Passes", "Verified"})
+	table.SetAutoWrapText(false)
+	for _, r := range reports {
+		computed := r.Rows[0]
+		verified := "ok"
+		switch {
+		case r.pending():
+			verified = "pending"
+		case r.VotesPruned:
+			verified = "votes pruned"
+		case len(r.Mismatches) > 0:
+			verified = "MISMATCH"
+		}
+		table.Append([]string{
+			strconv.FormatUint(r.ProposalID, 10),
+			r.Status,
+			h.Comma(int64(r.Votes)),
+			human(computed.Yes),
+			human(computed.No),
+			human(computed.NoWithVeto),
+			human(computed.Abstain),
+			fmt.Sprint(r.Outcome.Passes),
+			verified,
+		})
+	}
+	table.Render()
+}
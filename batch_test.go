@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+)
+
+func TestParseProposalIDs(t *testing.T) {
+	tests := []struct {
+		name            string
+		s               string
+		expectedPropIDs []uint64
+		expectedErr     string
+	}{
+		{
+			name:            "list and ranges",
+			s:               "848, 850-852,860",
+			expectedPropIDs: []uint64{848, 850, 851, 852, 860},
+		},
+		{
+			name:        "invalid id",
+			s:           "848,abc",
+			expectedErr: `invalid proposal id in "abc"`,
+		},
+		{
+			name:        "zero id",
+			s:           "0-2",
+			expectedErr: `invalid proposal id in "0-2"`,
+		},
+		{
+			name:        "reversed range",
+			s:           "852-850",
+			expectedErr: `invalid proposal range "852-850"`,
+		},
+		{
+			name:        "duplicate",
+			s:           "848,847-849",
+			expectedErr: "duplicate proposal id 848",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			propIDs, err := parseProposalIDs(tt.s)
+
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedPropIDs, propIDs)
+		})
+	}
+}
+
+func TestBatchTally(t *testing.T) {
+	var (
+		require = require.New(t)
+		assert  = assert.New(t)
+		src     = exportSource
+		rule    = hubRule{params: govtypes.DefaultTallyParams()}
+	)
+	src.propID = 0
+
+	reports, err := batchTally(src, []uint64{846, 847, 848}, rule, sdk.ZeroInt())
+
+	require.NoError(err)
+	require.Len(reports, 3)
+	// 846 was tallied before the pre-tally export, its votes are pruned so it
+	// is not verified
+	assert.EqualValues(846, reports[0].ProposalID)
+	assert.False(reports[0].pending())
+	assert.True(reports[0].VotesPruned)
+	assert.Zero(reports[0].Votes)
+	assert.Empty(reports[0].Mismatches)
+	// 847 is still in voting period, so it is not verified
+	assert.EqualValues(847, reports[1].ProposalID)
+	assert.True(reports[1].pending())
+	assert.False(reports[1].VotesPruned)
+	assert.Equal(1, reports[1].Votes)
+	assert.Empty(reports[1].Mismatches)
+	// 848 tally is the same as a single proposal tally
+	assert.EqualValues(848, reports[2].ProposalID)
+	assert.False(reports[2].pending())
+	assert.False(reports[2].VotesPruned)
+	assert.Equal(2, reports[2].Votes)
+	assert.Empty(reports[2].Mismatches)
+	prop, err := parseProp(exportSource)
+	require.NoError(err)
+	assert.Equal(prop.FinalTallyResult.Yes.String(), reports[2].Rows[0].Yes.String())
+	// the report keys are snake case like the tally rows keys
+	bz, err := json.Marshal(reports[2])
+	require.NoError(err)
+	var keys map[string]json.RawMessage
+	require.NoError(json.Unmarshal(bz, &keys))
+	for _, key := range []string{"proposal_id", "status", "votes", "rows", "outcome", "votes_pruned", "mismatches"} {
+		assert.Contains(keys, key)
+	}
+	assert.Len(keys, 7)
+	assert.Contains(string(keys["outcome"]), `"quorum_reached":true`)
+
+	_, err = batchTally(src, []uint64{848, 900}, rule, sdk.ZeroInt())
+	require.EqualError(err, "proposal 900 not found in testdata/export-tally.json")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	// compressedExts are the extensions tried when a file doesn't exist.
+	compressedExts = []string{".gz", ".zst"}
+
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// findFile returns path if it exists, or else its first existing compressed
+// variant.
+func findFile(path string) (string, error) {
+	_, err := os.Stat(path)
+	if !errors.Is(err, fs.ErrNotExist) {
+		return path, err
+	}
+	for _, ext := range compressedExts {
+		if _, err := os.Stat(path + ext); err == nil {
+			return path + ext, nil
+		}
+	}
+	return "", err
+}
+
+// openFile opens the file found by findFile. If the file content is gzip or
+// zstd compressed, which is detected with magic bytes, it is decompressed on
+// the fly.
+func openFile(path string) (io.ReadCloser, error) {
+	path, err := findFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(f)
+	// Peek returns an error when the file is smaller than the magic, in that
+	// case the file can't be compressed.
+	magic, _ := br.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return readCloser{zr, func() error {
+			zr.Close()
+			return f.Close()
+		}}, nil
+
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return readCloser{zr, func() error {
+			zr.Close()
+			return f.Close()
+		}}, nil
+	}
+	return readCloser{br, f.Close}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r readCloser) Close() error {
+	return r.close()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenFile(t *testing.T) {
+	var (
+		dir          = t.TempDir()
+		content, err = os.ReadFile("testdata/tally-votes.json")
+		gzipWrite    = func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }
+		zstdWrite    = func(w io.Writer) io.WriteCloser {
+			zw, err := zstd.NewWriter(w)
+			require.NoError(t, err)
+			return zw
+		}
+	)
+	require.NoError(t, err)
+	tests := []struct {
+		name      string
+		file      string
+		compress  func(io.Writer) io.WriteCloser
+		openPath  string
+		expectErr bool
+	}{
+		{
+			name:     "not compressed",
+			file:     "plain.json",
+			openPath: "plain.json",
+		},
+		{
+			name:     "gzip variant",
+			file:     "votes.json.gz",
+			compress: gzipWrite,
+			openPath: "votes.json",
+		},
+		{
+			name:     "zstd variant",
+			file:     "votes2.json.zst",
+			compress: zstdWrite,
+			openPath: "votes2.json",
+		},
+		{
+			name:     "compressed content without extension",
+			file:     "votes3.json",
+			compress: zstdWrite,
+			openPath: "votes3.json",
+		},
+		{
+			name:      "not found",
+			openPath:  "votes4.json",
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			if tt.file != "" {
+				f, err := os.Create(filepath.Join(dir, tt.file))
+				require.NoError(err)
+				if tt.compress != nil {
+					w := tt.compress(f)
+					_, err = w.Write(content)
+					require.NoError(err)
+					require.NoError(w.Close())
+				} else {
+					_, err = f.Write(content)
+					require.NoError(err)
+				}
+				require.NoError(f.Close())
+			}
+
+			r, err := openFile(filepath.Join(dir, tt.openPath))
+
+			if tt.expectErr {
+				require.ErrorIs(err, os.ErrNotExist)
+				return
+			}
+			require.NoError(err)
+			bz, err := io.ReadAll(r)
+			require.NoError(err)
+			require.NoError(r.Close())
+			assert.Equal(t, string(content), string(bz))
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	h "github.com/dustin/go-humanize"
+	"github.com/olekukonko/tablewriter"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+)
+
+// concentration measures how concentrated the voting power of a vote option
+// is among the entities which cast it: the validators, with the voting power
+// inherited from their delegators, and the delegators who voted.
+type concentration struct {
+	Option      string
+	Entities    int
+	VotingPower sdk.Dec
+	// Nakamoto33 and Nakamoto50 are the minimum number of entities which
+	// reach 33% and 50% of the voting power of the option.
+	Nakamoto33 int
+	Nakamoto50 int
+	// Gini is the Gini coefficient of the voting power of the entities.
+	Gini sdk.Dec
+	// TopShare is the share of the voting power of the TopN largest entities.
+	TopN     int
+	TopShare sdk.Dec
+}
+
+// newConcentrations returns the concentration of each vote option of the
+// tally result.
+func newConcentrations(res tallyResult, topN int) []concentration {
+	var concentrations []concentration
+	for _, option := range []govtypes.VoteOption{
+		govtypes.OptionYes, govtypes.OptionNo, govtypes.OptionNoWithVeto, govtypes.OptionAbstain,
+	} {
+		var powers []sdk.Dec
+		for _, val := range res.Validators {
+			if p, ok := val.VotingPower[option]; ok && p.IsPositive() {
+				powers = append(powers, p)
+			}
+		}
+		for _, voter := range res.Voters {
+			if p, ok := voter[option]; ok && p.IsPositive() {
+				powers = append(powers, p)
+			}
+		}
+		c := newConcentration(powers, topN)
+		c.Option = option.String()
+		concentrations = append(concentrations, c)
+	}
+	return concentrations
+}
+
+// newConcentration computes the concentration of the positive powers.
+func newConcentration(powers []sdk.Dec, topN int) concentration {
+	// Largest first
+	sort.Slice(powers, func(i, j int) bool { return powers[i].GT(powers[j]) })
+	c := concentration{
+		Entities:    len(powers),
+		VotingPower: sdk.ZeroDec(),
+		Gini:        sdk.ZeroDec(),
+		TopN:        topN,
+		TopShare:    sdk.ZeroDec(),
+	}
+	for _, p := range powers {
+		c.VotingPower = c.VotingPower.Add(p)
+	}
+	if c.VotingPower.IsZero() {
+		return c
+	}
+	c.Nakamoto33 = nakamotoCoefficient(powers, c.VotingPower, sdk.NewDecWithPrec(33, 2))
+	c.Nakamoto50 = nakamotoCoefficient(powers, c.VotingPower, sdk.NewDecWithPrec(50, 2))
+	top := sdk.ZeroDec()
+	for i := 0; i < topN && i < len(powers); i++ {
+		top = top.Add(powers[i])
+	}
+	c.TopShare = top.Quo(c.VotingPower)
+	// G = 2 * sum(i * x_i) / (n * sum(x)) - (n + 1) / n, with x sorted in
+	// ascending order and i starting at 1.
+	n := int64(len(powers))
+	weighted := sdk.ZeroDec()
+	for i, p := range powers {
+		weighted = weighted.Add(p.MulInt64(n - int64(i)))
+	}
+	c.Gini = weighted.MulInt64(2).Quo(c.VotingPower.MulInt64(n)).
+		Sub(sdk.NewDec(n + 1).QuoInt64(n))
+	return c
+}
+
+// nakamotoCoefficient returns the minimum number of powers, sorted from the
+// largest, whose sum reaches ratio of total.
+func nakamotoCoefficient(powers []sdk.Dec, total, ratio sdk.Dec) int {
+	var (
+		target = total.Mul(ratio)
+		sum    = sdk.ZeroDec()
+	)
+	for i, p := range powers {
+		sum = sum.Add(p)
+		if sum.GTE(target) {
+			return i + 1
+		}
+	}
+	return len(powers)
+}
+
+func printConcentrations(w io.Writer, concentrations []concentration) {
+	fmt.Fprintln(w, "--- CONCENTRATION ---")
+	table := tablewriter.NewWriter(w)
+	topN := 0
+	if len(concentrations) > 0 {
+		topN = concentrations[0].TopN
+	}
+	table.SetHeader([]string{
+		"", "Entities", "Voting power", "Nakamoto 33%", "Nakamoto 50%", "Gini", fmt.Sprintf("Top %d share", topN),
+	})
+	table.SetAutoWrapText(false)
+	for _, c := range concentrations {
+		table.Append([]string{
+			c.Option,
+			h.Comma(int64(c.Entities)),
+			human(c.VotingPower.TruncateInt()),
+			h.Comma(int64(c.Nakamoto33)),
+			h.Comma(int64(c.Nakamoto50)),
+			fmt.Sprintf("%.4f", c.Gini.MustFloat64()),
+			percent(c.TopShare),
+		})
+	}
+	table.Render()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+)
+
+func TestNewConcentration(t *testing.T) {
+	decs := func(amounts ...int64) []sdk.Dec {
+		var ds []sdk.Dec
+		for _, a := range amounts {
+			ds = append(ds, sdk.NewDec(a))
+		}
+		return ds
+	}
+	tests := []struct {
+		name               string
+		powers             []sdk.Dec
+		topN               int
+		expectedNakamoto33 int
+		expectedNakamoto50 int
+		expectedGini       string
+		expectedTopShare   string
+	}{
+		{
+			name:             "no power",
+			topN:             2,
+			expectedGini:     "0.000000000000000000",
+			expectedTopShare: "0.000000000000000000",
+		},
+		{
+			name:               "equal powers",
+			powers:             decs(10, 10, 10, 10),
+			topN:               2,
+			expectedNakamoto33: 2,
+			expectedNakamoto50: 2,
+			expectedGini:       "0.000000000000000000",
+			expectedTopShare:   "0.500000000000000000",
+		},
+		{
+			name:               "concentrated powers",
+			powers:             decs(1, 3),
+			topN:               1,
+			expectedNakamoto33: 1,
+			expectedNakamoto50: 1,
+			expectedGini:       "0.250000000000000000",
+			expectedTopShare:   "0.750000000000000000",
+		},
+		{
+			name:               "unsorted powers",
+			powers:             decs(10, 60, 30),
+			topN:               5,
+			expectedNakamoto33: 1,
+			expectedNakamoto50: 1,
+			expectedGini:       "0.333333333333333334",
+			expectedTopShare:   "1.000000000000000000",
+		},
+		{
+			name:               "long tail",
+			powers:             decs(30, 20, 10, 10, 10, 10, 10),
+			topN:               3,
+			expectedNakamoto33: 2,
+			expectedNakamoto50: 2,
+			expectedGini:       "0.228571428571428572",
+			expectedTopShare:   "0.600000000000000000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConcentration(tt.powers, tt.topN)
+
+			assert.Equal(t, len(tt.powers), c.Entities)
+			assert.Equal(t, tt.expectedNakamoto33, c.Nakamoto33)
+			assert.Equal(t, tt.expectedNakamoto50, c.Nakamoto50)
+			assert.Equal(t, tt.expectedGini, c.Gini.String())
+			assert.Equal(t, tt.expectedTopShare, c.TopShare.String())
+		})
+	}
+}
+
+func TestNewConcentrations(t *testing.T) {
+	res := tallyResult{
+		Validators: map[string]validatorTally{
+			"val": {VotingPower: map[govtypes.VoteOption]sdk.Dec{govtypes.OptionYes: sdk.NewDec(60)}},
+		},
+		Voters: map[string]map[govtypes.VoteOption]sdk.Dec{
+			"acc0": {govtypes.OptionYes: sdk.NewDec(30), govtypes.OptionNo: sdk.NewDec(10)},
+			"acc1": {govtypes.OptionYes: sdk.NewDec(10)},
+		},
+	}
+
+	concentrations := newConcentrations(res, 1)
+
+	assert.Len(t, concentrations, 4)
+	yes := concentrations[0]
+	assert.Equal(t, govtypes.OptionYes.String(), yes.Option)
+	assert.Equal(t, 3, yes.Entities)
+	assert.Equal(t, sdk.NewDec(100).String(), yes.VotingPower.String())
+	assert.Equal(t, 1, yes.Nakamoto50)
+	assert.Equal(t, "0.600000000000000000", yes.TopShare.String())
+	no := concentrations[1]
+	assert.Equal(t, 1, no.Entities)
+	assert.Equal(t, 0, concentrations[2].Entities)
+}
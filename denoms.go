@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	transfertypes "github.com/cosmos/ibc-go/v4/modules/apps/transfer/types"
+)
+
+// defaultDenoms only counts the native token.
+const defaultDenoms = "uatom=1"
+
+// parseDenomWeights parses a comma separated list of denom=weight, where the
+// weight is optional and defaults to 1. The denom can be:
+//   - a native denom, like uatom
+//   - an IBC denom, like ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2
+//   - an IBC denom path, like transfer/channel-141/uatom
+//   - */BASE_DENOM, which matches every IBC denom of that base denom
+//
+// IBC denoms are resolved with the denom traces, and the returned map is
+// indexed by the denoms as found in the balances.
+func parseDenomWeights(s string, traces transfertypes.Traces) (map[string]sdk.Dec, error) {
+	denomWeights := make(map[string]sdk.Dec)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		denom, weightStr, found := strings.Cut(entry, "=")
+		weight := sdk.OneDec()
+		if found {
+			var err error
+			weight, err = sdk.NewDecFromStr(weightStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid weight for denom %s: %w", denom, err)
+			}
+		}
+		denoms, err := resolveDenom(denom, traces)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range denoms {
+			denomWeights[d] = weight
+		}
+	}
+	if len(denomWeights) == 0 {
+		return nil, fmt.Errorf("no denom in %q", s)
+	}
+	return denomWeights, nil
+}
+
+// resolveDenom returns the denoms as found in the balances that match denom.
+func resolveDenom(denom string, traces transfertypes.Traces) ([]string, error) {
+	switch {
+	case strings.HasPrefix(denom, "*/"):
+		baseDenom := strings.TrimPrefix(denom, "*/")
+		var denoms []string
+		for _, t := range traces {
+			if t.BaseDenom == baseDenom {
+				denoms = append(denoms, t.IBCDenom())
+			}
+		}
+		if len(denoms) == 0 {
+			return nil, fmt.Errorf("no denom trace with base denom %s", baseDenom)
+		}
+		return denoms, nil
+
+	case strings.HasPrefix(denom, "ibc/"):
+		for _, t := range traces {
+			if t.IBCDenom() == denom {
+				return []string{denom}, nil
+			}
+		}
+		return nil, fmt.Errorf("denom %s not found in denom traces", denom)
+
+	case strings.Contains(denom, "/"):
+		ibcDenom := transfertypes.ParseDenomTrace(denom).IBCDenom()
+		for _, t := range traces {
+			if t.IBCDenom() == ibcDenom {
+				return []string{ibcDenom}, nil
+			}
+		}
+		return nil, fmt.Errorf("denom path %s not found in denom traces", denom)
+	}
+	// Native denom
+	return []string{denom}, nil
+}
+
+// liquidAmount returns the sum of the coins weighted by denomWeights. Coins
+// of other denoms are ignored.
+func liquidAmount(coins sdk.Coins, denomWeights map[string]sdk.Dec) sdk.Dec {
+	amount := sdk.ZeroDec()
+	for _, c := range coins {
+		if weight, ok := denomWeights[c.Denom]; ok {
+			amount = amount.Add(c.Amount.ToDec().Mul(weight))
+		}
+	}
+	return amount
+}
+
+// shareDenomValidator returns the validator address of a LSM tokenized shares
+// denom, which has the VALIDATOR_ADDRESS/RECORD_ID format.
+func shareDenomValidator(denom string) (string, bool) {
+	valAddr, recordID, found := strings.Cut(denom, "/")
+	if !found || !strings.HasPrefix(valAddr, sdk.GetConfig().GetBech32ValidatorAddrPrefix()+"1") {
+		return "", false
+	}
+	if _, err := strconv.ParseUint(recordID, 10, 64); err != nil {
+		return "", false
+	}
+	return valAddr, true
+}
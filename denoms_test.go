@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	transfertypes "github.com/cosmos/ibc-go/v4/modules/apps/transfer/types"
+)
+
+func TestParseDenomWeights(t *testing.T) {
+	var (
+		osmoTrace  = transfertypes.ParseDenomTrace("transfer/channel-0/uosmo")
+		atomTrace  = transfertypes.ParseDenomTrace("transfer/channel-141/transfer/channel-0/uatom")
+		atomTrace2 = transfertypes.ParseDenomTrace("transfer/channel-1/uatom")
+		traces     = transfertypes.Traces{osmoTrace, atomTrace, atomTrace2}
+	)
+	tests := []struct {
+		name                 string
+		denoms               string
+		expectedDenomWeights map[string]sdk.Dec
+		expectedErr          string
+	}{
+		{
+			name:   "default",
+			denoms: defaultDenoms,
+			expectedDenomWeights: map[string]sdk.Dec{
+				"uatom": sdk.OneDec(),
+			},
+		},
+		{
+			name:   "default weight",
+			denoms: "uatom,stake=0.5",
+			expectedDenomWeights: map[string]sdk.Dec{
+				"uatom": sdk.OneDec(),
+				"stake": sdk.NewDecWithPrec(5, 1),
+			},
+		},
+		{
+			name:   "ibc denom and path",
+			denoms: "uatom=1, " + osmoTrace.IBCDenom() + "=0.1,transfer/channel-141/transfer/channel-0/uatom=0.5",
+			expectedDenomWeights: map[string]sdk.Dec{
+				"uatom":              sdk.OneDec(),
+				osmoTrace.IBCDenom(): sdk.NewDecWithPrec(1, 1),
+				atomTrace.IBCDenom(): sdk.NewDecWithPrec(5, 1),
+			},
+		},
+		{
+			name:   "all ibc denoms of a base denom",
+			denoms: "uatom,*/uatom=0.9",
+			expectedDenomWeights: map[string]sdk.Dec{
+				"uatom":               sdk.OneDec(),
+				atomTrace.IBCDenom():  sdk.NewDecWithPrec(9, 1),
+				atomTrace2.IBCDenom(): sdk.NewDecWithPrec(9, 1),
+			},
+		},
+		{
+			name:        "unknown ibc denom",
+			denoms:      "ibc/ABCD",
+			expectedErr: "denom ibc/ABCD not found in denom traces",
+		},
+		{
+			name:        "unknown denom path",
+			denoms:      "transfer/channel-2/uatom",
+			expectedErr: "denom path transfer/channel-2/uatom not found in denom traces",
+		},
+		{
+			name:        "unknown base denom",
+			denoms:      "*/ujuno",
+			expectedErr: "no denom trace with base denom ujuno",
+		},
+		{
+			name:        "invalid weight",
+			denoms:      "uatom=x",
+			expectedErr: "invalid weight for denom uatom",
+		},
+		{
+			name:        "empty",
+			denoms:      " ,",
+			expectedErr: `no denom in " ,"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			denomWeights, err := parseDenomWeights(tt.denoms, traces)
+
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedDenomWeights, denomWeights)
+		})
+	}
+}
+
+func TestLiquidAmount(t *testing.T) {
+	coins := sdk.NewCoins(
+		sdk.NewInt64Coin("uatom", 100),
+		sdk.NewInt64Coin("ibc/1", 10),
+		sdk.NewInt64Coin("stake", 1000),
+	)
+	denomWeights := map[string]sdk.Dec{
+		"uatom": sdk.OneDec(),
+		"ibc/1": sdk.NewDecWithPrec(5, 1),
+	}
+
+	amount := liquidAmount(coins, denomWeights)
+
+	assert.Equal(t, sdk.NewDec(105).String(), amount.String())
+}
@@ -24,7 +24,67 @@ var (
 	malus        = sdk.NewDecWithPrec(97, 2)  // -3% malus
 )
 
-func distribution(accounts []Account) (map[string]sdk.Dec, error) {
+// distributionParams holds the configurable parameters of the distribution.
+type distributionParams struct {
+	// unbondingMultiplier applies to the tokens in the unbonding period. If
+	// nil, they get the same multiplier as the liquid amount.
+	unbondingMultiplier *sdk.Dec
+	// lockedMultiplier applies to the part of the liquid amount still locked
+	// by a vesting schedule. If nil, it gets the liquid multiplier.
+	lockedMultiplier *sdk.Dec
+}
+
+// newDistributionParams parses the multipliers of the distribution params,
+// an empty multiplier leaves the default one.
+func newDistributionParams(unbondingMultiplier, lockedMultiplier string) (distributionParams, error) {
+	var params distributionParams
+	if unbondingMultiplier != "" {
+		m, err := sdk.NewDecFromStr(unbondingMultiplier)
+		if err != nil {
+			return params, fmt.Errorf("unbonding multiplier: %w", err)
+		}
+		params.unbondingMultiplier = &m
+	}
+	if lockedMultiplier != "" {
+		m, err := sdk.NewDecFromStr(lockedMultiplier)
+		if err != nil {
+			return params, fmt.Errorf("locked multiplier: %w", err)
+		}
+		params.lockedMultiplier = &m
+	}
+	return params, nil
+}
+
+func distribution(accounts []Account, params distributionParams) (map[string]sdk.Dec, error) {
+	percs, blend, totalSupply := votePercs(accounts)
+	for k, v := range percs {
+		fmt.Println(k, v)
+	}
+	fmt.Println("BLEND", blend)
+
+	totalAirdrop := sdk.ZeroDec()
+	res := make(map[string]sdk.Dec)
+	for _, acc := range accounts {
+		if slices.Contains(icfWallets, acc.Address) {
+			// Slash ICF
+			continue
+		}
+		airdrop := params.multipliers(acc.VotePercs, blend).airdrop(acc)
+		totalAirdrop = totalAirdrop.Add(airdrop)
+		res[acc.Address] = airdrop
+	}
+	fmt.Println("TOTAL SUPPLY ", humand(totalSupply))
+	fmt.Println("TOTAL AIRDROP", humand(totalAirdrop))
+	fmt.Println("RATIO", totalAirdrop.Quo(totalSupply))
+	// output
+	// address : airdropAmount
+	return res, nil
+}
+
+// votePercs sets the VotePercs of the accounts, and returns the percentage of
+// each vote option relative to the total voted amount, the blend of these
+// percentages and the total supply of the accounts.
+func votePercs(accounts []Account) (map[govtypes.VoteOption]sdk.Dec, sdk.Dec, sdk.Dec) {
 	// Get amounts of Y, N and NWV
 	var (
 		amts        = newVoteMap()
@@ -35,38 +95,64 @@ func distribution(accounts []Account) (map[string]sdk.Dec, error) {
 		// init VotePercs
 		acc := &accounts[i]
 		acc.VotePercs = newVoteMap()
-		totalSupply = totalSupply.Add(acc.StakedAmount).Add(acc.LiquidAmount)
+		if acc.UnbondingAmount.IsNil() {
+			// accounts.json generated before unbondings were tracked
+			acc.UnbondingAmount = sdk.ZeroDec()
+		}
+		if acc.LockedAmount.IsNil() {
+			// accounts.json generated before vesting was tracked
+			acc.LockedAmount = sdk.ZeroDec()
+		}
+		if acc.RewardsAmount.IsNil() {
+			// accounts.json generated before rewards were tracked
+			acc.RewardsAmount = sdk.ZeroDec()
+		}
+		totalSupply = totalSupply.Add(acc.StakedAmount).Add(acc.LiquidAmount).
+			Add(acc.UnbondingAmount).Add(acc.RewardsAmount)
 		if acc.StakedAmount.IsZero() {
 			// No stake, consider non-voter
 			acc.VotePercs[govtypes.OptionEmpty] = sdk.NewDec(1)
 			continue
 		}
+		// addDelegationVote adds the vote of the validator of del
+		addDelegationVote := func(del Delegation) {
+			// Compute percentage of the delegation over the total staked amount
+			delPerc := del.Amount.Quo(acc.StakedAmount)
+			if len(del.Vote) == 0 {
+				// user didn't vote and delegation didn't either, use the UNSPECIFIED
+				// vote option to track it.
+				acc.VotePercs[govtypes.OptionEmpty] = acc.VotePercs[govtypes.OptionEmpty].
+					Add(sdk.NewDec(1).Mul(delPerc))
+				return
+			}
+			for _, vote := range del.Vote {
+				acc.VotePercs[vote.Option] = acc.VotePercs[vote.Option].Add(vote.Weight.Mul(delPerc))
+
+				amt := del.Amount.Mul(vote.Weight)
+				amts[vote.Option] = amts[vote.Option].Add(amt)
+				totalAmt = totalAmt.Add(amt)
+			}
+		}
 		if len(acc.Vote) == 0 {
 			// not a direct voter, check for delegated votes
 			for _, del := range acc.Delegations {
-				// Compute percentage of the delegation over the total staked amount
-				delPerc := del.Amount.Quo(acc.StakedAmount)
-				if len(del.Vote) == 0 {
-					// user didn't vote and delegation didn't either, use the UNSPECIFIED
-					// vote option to track it.
-					acc.VotePercs[govtypes.OptionEmpty] = acc.VotePercs[govtypes.OptionEmpty].
-						Add(sdk.NewDec(1).Mul(delPerc))
-				} else {
-					for _, vote := range del.Vote {
-						acc.VotePercs[vote.Option] = acc.VotePercs[vote.Option].Add(vote.Weight.Mul(delPerc))
-
-						amt := del.Amount.Mul(vote.Weight)
-						amts[vote.Option] = amts[vote.Option].Add(amt)
-						totalAmt = totalAmt.Add(amt)
-					}
-				}
+				addDelegationVote(del)
 			}
 		} else {
-			// direct voter
+			// direct voter, except for the share tokens which follow the
+			// validator vote
+			directAmt := acc.StakedAmount
+			for _, del := range acc.Delegations {
+				if del.ShareTokens {
+					directAmt = directAmt.Sub(del.Amount)
+					addDelegationVote(del)
+				}
+			}
+			directPerc := directAmt.Quo(acc.StakedAmount)
 			for _, vote := range acc.Vote {
-				acc.VotePercs[vote.Option] = vote.Weight
+				acc.VotePercs[vote.Option] = acc.VotePercs[vote.Option].Add(vote.Weight.Mul(directPerc))
 
-				amt := acc.StakedAmount.Mul(vote.Weight)
+				amt := directAmt.Mul(vote.Weight)
 				amts[vote.Option] = amts[vote.Option].Add(amt)
 				totalAmt = totalAmt.Add(amt)
 			}
@@ -76,47 +162,60 @@ func distribution(accounts []Account) (map[string]sdk.Dec, error) {
 	percs := make(map[govtypes.VoteOption]sdk.Dec)
 	for k, v := range amts {
 		percs[k] = v.Quo(totalAmt)
-		fmt.Println(k, percs[k])
 	}
 	// Compute blend
 	blend := percs[govtypes.OptionYes].
 		Add(percs[govtypes.OptionNo].Mul(noMultiplier)).
 		Add(percs[govtypes.OptionNoWithVeto].Mul(noMultiplier))
-	fmt.Println("BLEND", blend)
+	return percs, blend, totalSupply
+}
 
-	totalAirdrop := sdk.ZeroDec()
-	res := make(map[string]sdk.Dec)
-	for _, acc := range accounts {
-		if slices.Contains(icfWallets, acc.Address) {
-			// Slash ICF
-			continue
-		}
-		percs := acc.VotePercs
-		// stakingMultiplier details:
-		// Yes:					x 1
-		// No:         	x noMultiplier
-		// NoWithVeto: 	x noMultiplier x bonus
-		// Abstain:    	x blend
-		// Didn't vote: x blend x malus
-		stakingMultiplier := percs[govtypes.OptionYes].
-			Add(percs[govtypes.OptionNo].Mul(noMultiplier)).
-			Add(percs[govtypes.OptionNoWithVeto].Mul(noMultiplier).Mul(bonus)).
-			Add(percs[govtypes.OptionAbstain].Mul(blend)).
-			Add(percs[govtypes.OptionEmpty].Mul(blend).Mul(malus))
-		// Liquid amount gets the same multiplier as those who didn't vote.
-		liquidMultiplier := blend.Mul(malus)
+// accountMultipliers are the multipliers applied to the amounts of an account.
+type accountMultipliers struct {
+	Staking   sdk.Dec
+	Liquid    sdk.Dec
+	Locked    sdk.Dec
+	Unbonding sdk.Dec
+}
 
-		airdrop := acc.LiquidAmount.Mul(liquidMultiplier).
-			Add(acc.StakedAmount.Mul(stakingMultiplier))
-		totalAirdrop = totalAirdrop.Add(airdrop)
-		res[acc.Address] = airdrop
+// multipliers returns the multipliers of an account given its VotePercs.
+func (p distributionParams) multipliers(percs map[govtypes.VoteOption]sdk.Dec, blend sdk.Dec) accountMultipliers {
+	// stakingMultiplier details:
+	// Yes:					x 1
+	// No:         	x noMultiplier
+	// NoWithVeto: 	x noMultiplier x bonus
+	// Abstain:    	x blend
+	// Didn't vote: x blend x malus
+	stakingMultiplier := percs[govtypes.OptionYes].
+		Add(percs[govtypes.OptionNo].Mul(noMultiplier)).
+		Add(percs[govtypes.OptionNoWithVeto].Mul(noMultiplier).Mul(bonus)).
+		Add(percs[govtypes.OptionAbstain].Mul(blend)).
+		Add(percs[govtypes.OptionEmpty].Mul(blend).Mul(malus))
+	// Liquid amount gets the same multiplier as those who didn't vote.
+	liquidMultiplier := blend.Mul(malus)
+	m := accountMultipliers{
+		Staking:   stakingMultiplier,
+		Liquid:    liquidMultiplier,
+		Locked:    liquidMultiplier,
+		Unbonding: liquidMultiplier,
 	}
-	fmt.Println("TOTAL SUPPLY ", humand(totalSupply))
-	fmt.Println("TOTAL AIRDROP", humand(totalAirdrop))
-	fmt.Println("RATIO", totalAirdrop.Quo(totalSupply))
-	// output
-	// address : airdropAmount
-	return res, nil
+	if p.unbondingMultiplier != nil {
+		m.Unbonding = *p.unbondingMultiplier
+	}
+	if p.lockedMultiplier != nil {
+		m.Locked = *p.lockedMultiplier
+	}
+	return m
+}
+
+// airdrop returns the airdrop of acc.
+func (m accountMultipliers) airdrop(acc Account) sdk.Dec {
+	// Locked tokens are part of the liquid amount, and rewards become liquid
+	// once withdrawn.
+	return acc.LiquidAmount.Sub(acc.LockedAmount).Add(acc.RewardsAmount).Mul(m.Liquid).
+		Add(acc.LockedAmount.Mul(m.Locked)).
+		Add(acc.StakedAmount.Mul(m.Staking)).
+		Add(acc.UnbondingAmount.Mul(m.Unbonding))
 }
 
 func newVoteMap() map[govtypes.VoteOption]sdk.Dec {
@@ -88,7 +88,7 @@ func TestDistribution(t *testing.T) {
 			assert := assert.New(t)
 			fmt.Println(sdk.NewDecWithPrec(55, 1).Mul(malus))
 
-			res, err := distribution(tt.accounts)
+			res, err := distribution(tt.accounts, distributionParams{})
 
 			require.NoError(err)
 			assert.Equal(len(tt.expectedRes), len(res), "unexpected number of res")
@@ -101,3 +101,91 @@ func TestDistribution(t *testing.T) {
 		})
 	}
 }
+
+func TestDistributionParams(t *testing.T) {
+	voteYes := govtypes.WeightedVoteOptions{{
+		Option: govtypes.OptionYes,
+		Weight: sdk.NewDec(1),
+	}}
+	newAccounts := func() []Account {
+		return []Account{
+			{
+				Address:         "liquid",
+				LiquidAmount:    sdk.NewDec(10),
+				StakedAmount:    sdk.NewDec(2),
+				UnbondingAmount: sdk.ZeroDec(),
+				LockedAmount:    sdk.ZeroDec(),
+				Vote:            voteYes,
+			},
+			{
+				Address:         "unbonding",
+				LiquidAmount:    sdk.ZeroDec(),
+				StakedAmount:    sdk.NewDec(2),
+				UnbondingAmount: sdk.NewDec(10),
+				LockedAmount:    sdk.ZeroDec(),
+				Vote:            voteYes,
+			},
+			{
+				Address:         "locked",
+				LiquidAmount:    sdk.NewDec(10),
+				StakedAmount:    sdk.NewDec(2),
+				UnbondingAmount: sdk.ZeroDec(),
+				LockedAmount:    sdk.NewDec(10),
+				Vote:            voteYes,
+			},
+			{
+				// accounts.json without UnbondingAmount and LockedAmount
+				Address:      "none",
+				LiquidAmount: sdk.ZeroDec(),
+				StakedAmount: sdk.NewDec(2),
+				Vote:         voteYes,
+			},
+		}
+	}
+	half := sdk.NewDecWithPrec(5, 1)
+	tests := []struct {
+		name             string
+		params           distributionParams
+		expectedUnbonded func(res map[string]sdk.Dec) sdk.Dec
+		expectedLocked   func(res map[string]sdk.Dec) sdk.Dec
+	}{
+		{
+			name: "default to liquid multiplier",
+			expectedUnbonded: func(res map[string]sdk.Dec) sdk.Dec {
+				return res["liquid"]
+			},
+			expectedLocked: func(res map[string]sdk.Dec) sdk.Dec {
+				return res["liquid"]
+			},
+		},
+		{
+			name:   "custom unbonding multiplier",
+			params: distributionParams{unbondingMultiplier: &half},
+			expectedUnbonded: func(res map[string]sdk.Dec) sdk.Dec {
+				return res["none"].Add(sdk.NewDec(5))
+			},
+			expectedLocked: func(res map[string]sdk.Dec) sdk.Dec {
+				return res["liquid"]
+			},
+		},
+		{
+			name:   "custom locked multiplier",
+			params: distributionParams{lockedMultiplier: &half},
+			expectedUnbonded: func(res map[string]sdk.Dec) sdk.Dec {
+				return res["liquid"]
+			},
+			expectedLocked: func(res map[string]sdk.Dec) sdk.Dec {
+				return res["none"].Add(sdk.NewDec(5))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := distribution(newAccounts(), tt.params)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedUnbonded(res).String(), res["unbonding"].String())
+			assert.Equal(t, tt.expectedLocked(res).String(), res["locked"].String())
+		})
+	}
+}
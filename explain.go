@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	h "github.com/dustin/go-humanize"
+	"github.com/olekukonko/tablewriter"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+// explanation details how an account counts in the tally.
+type explanation struct {
+	Address string
+	Balance sdk.Coins
+	// Vote is the vote cast by the account, empty if it didn't vote.
+	Vote        govtypes.WeightedVoteOptions
+	Delegations []delegationExplanation
+	// VotingPower is the contribution of the account to the tally, per vote
+	// option.
+	VotingPower map[govtypes.VoteOption]sdk.Dec
+}
+
+// delegationExplanation details how a delegation counts in the tally.
+type delegationExplanation struct {
+	ValidatorAddress string
+	Shares           sdk.Dec
+	// Active is false when the validator isn't in the active set, in which
+	// case the delegation has no voting power.
+	Active bool
+	// Tokens are the shares converted to tokens with the validator
+	// tokens/shares ratio, that is the voting power of the delegation.
+	Tokens        sdk.Dec
+	ValidatorVote govtypes.WeightedVoteOptions
+	// Vote is the vote applied to the delegation: the account vote, or the
+	// validator vote when Inherited is true. Overrides is true when the
+	// account vote replaces the vote of another account's validator.
+	Vote      govtypes.WeightedVoteOptions
+	Inherited bool
+	Overrides bool
+	// ShareTokens is true for the LSM tokenized shares held by the account,
+	// which are delegated by a tokenize share record module account, so they
+	// always inherit the validator vote.
+	ShareTokens bool
+	// VotingPower is the contribution of the delegation to the tally, per
+	// vote option.
+	VotingPower map[govtypes.VoteOption]sdk.Dec
+}
+
+// newExplanation explains how the account addr counts in the tally, following
+// the x/gov vote inheritance.
+//
+// The LSM tokenized shares of the account are listed after its delegations,
+// like getAccounts counts them.
+func newExplanation(
+	addr string, votesByAddr map[string]govtypes.WeightedVoteOptions,
+	valsByAddr map[string]govtypes.ValidatorGovInfo, delegsByAddr map[string][]stakingtypes.Delegation,
+	balancesByAddr map[string]sdk.Coins,
+) explanation {
+	e := explanation{
+		Address:     addr,
+		Balance:     balancesByAddr[addr],
+		Vote:        votesByAddr[addr],
+		VotingPower: newVoteMap(),
+	}
+	for _, deleg := range delegsByAddr[addr] {
+		d := delegationExplanation{
+			ValidatorAddress: deleg.ValidatorAddress,
+			Shares:           deleg.GetShares(),
+			Tokens:           sdk.ZeroDec(),
+			Vote:             e.Vote,
+			VotingPower:      newVoteMap(),
+		}
+		val, ok := valsByAddr[deleg.ValidatorAddress]
+		if ok {
+			d.Active = true
+			d.Tokens = deleg.GetShares().MulInt(val.BondedTokens).Quo(val.DelegatorShares)
+			d.ValidatorVote = val.Vote
+		}
+		if len(d.Vote) == 0 {
+			d.Vote = d.ValidatorVote
+			d.Inherited = len(d.Vote) > 0
+		} else if len(d.ValidatorVote) > 0 {
+			// The validator vote is the vote of its operator account
+			valAddr, err := sdk.ValAddressFromBech32(deleg.ValidatorAddress)
+			d.Overrides = err != nil || sdk.AccAddress(valAddr).String() != addr
+		}
+		if d.Active {
+			for _, option := range d.Vote {
+				power := d.Tokens.Mul(option.Weight)
+				d.VotingPower[option.Option] = d.VotingPower[option.Option].Add(power)
+				e.VotingPower[option.Option] = e.VotingPower[option.Option].Add(power)
+			}
+		}
+		e.Delegations = append(e.Delegations, d)
+	}
+	for _, c := range e.Balance {
+		valAddr, ok := shareDenomValidator(c.Denom)
+		if !ok {
+			continue
+		}
+		// Share tokens map 1:1 with shares
+		d := delegationExplanation{
+			ValidatorAddress: valAddr,
+			Shares:           c.Amount.ToDec(),
+			Tokens:           sdk.ZeroDec(),
+			ShareTokens:      true,
+			VotingPower:      newVoteMap(),
+		}
+		if val, ok := valsByAddr[valAddr]; ok {
+			d.Active = true
+			d.Tokens = d.Shares.MulInt(val.BondedTokens).Quo(val.DelegatorShares)
+			d.ValidatorVote = val.Vote
+			for _, option := range val.Vote {
+				power := d.Tokens.Mul(option.Weight)
+				d.VotingPower[option.Option] = d.VotingPower[option.Option].Add(power)
+				e.VotingPower[option.Option] = e.VotingPower[option.Option].Add(power)
+			}
+		}
+		d.Vote = d.ValidatorVote
+		d.Inherited = len(d.Vote) > 0
+		e.Delegations = append(e.Delegations, d)
+	}
+	return e
+}
+
+// airdropExplanation details the airdrop of an account.
+type airdropExplanation struct {
+	// Account has its VotePercs computed by the distribution.
+	Account     Account
+	Blend       sdk.Dec
+	Multipliers accountMultipliers
+	// ICF is true for the ICF wallets, which get no airdrop.
+	ICF     bool
+	Airdrop sdk.Dec
+}
+
+// newAirdropExplanation explains the airdrop of the account addr, computed
+// like distribution does.
+func newAirdropExplanation(accounts []Account, addr string, params distributionParams) (airdropExplanation, error) {
+	_, blend, _ := votePercs(accounts)
+	i := slices.IndexFunc(accounts, func(acc Account) bool { return acc.Address == addr })
+	if i < 0 {
+		return airdropExplanation{}, fmt.Errorf("account %s not found", addr)
+	}
+	e := airdropExplanation{
+		Account:     accounts[i],
+		Blend:       blend,
+		Multipliers: params.multipliers(accounts[i].VotePercs, blend),
+		ICF:         slices.Contains(icfWallets, addr),
+		Airdrop:     sdk.ZeroDec(),
+	}
+	if !e.ICF {
+		e.Airdrop = e.Multipliers.airdrop(e.Account)
+	}
+	return e, nil
+}
+
+func printExplanation(e explanation) {
+	fmt.Printf("--- ACCOUNT %s ---\n", e.Address)
+	balance, vote := "-", "-"
+	if !e.Balance.IsZero() {
+		balance = e.Balance.String()
+	}
+	fmt.Printf("Balance: %s\n", balance)
+	if len(e.Vote) > 0 {
+		vote = voteString(e.Vote)
+	}
+	fmt.Printf("Vote: %s\n", vote)
+
+	fmt.Println("--- DELEGATIONS ---")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{
+		"Validator", "Shares", "Tokens", "Validator vote", "Applied vote",
+		"Yes", "No", "NoWithVeto", "Abstain",
+	})
+	table.SetAutoWrapText(false)
+	for _, d := range e.Delegations {
+		validator, tokens, validatorVote, vote := d.ValidatorAddress, "inactive", "-", "-"
+		if d.ShareTokens {
+			validator += " (tokenize share record)"
+		}
+		if d.Active {
+			tokens = amount(d.Tokens)
+		}
+		if len(d.ValidatorVote) > 0 {
+			validatorVote = voteString(d.ValidatorVote)
+		}
+		switch {
+		case d.Inherited:
+			vote = voteString(d.Vote) + " (inherited)"
+		case d.Overrides:
+			vote = voteString(d.Vote) + " (overrides validator)"
+		case len(d.Vote) > 0:
+			vote = voteString(d.Vote)
+		}
+		table.Append(append([]string{validator, amount(d.Shares), tokens, validatorVote, vote},
+			voteAmounts(d.VotingPower)...))
+	}
+	table.SetFooter(append([]string{"", "", "", "", "Tally contribution"}, voteAmounts(e.VotingPower)...))
+	table.Render()
+}
+
+func printAirdropExplanation(e airdropExplanation) {
+	fmt.Println("--- VOTE PERCENTAGES ---")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Yes", "No", "NoWithVeto", "Abstain", "Didn't vote"})
+	table.SetAutoWrapText(false)
+	var percs []string
+	for _, option := range []govtypes.VoteOption{
+		govtypes.OptionYes, govtypes.OptionNo, govtypes.OptionNoWithVeto, govtypes.OptionAbstain, govtypes.OptionEmpty,
+	} {
+		percs = append(percs, percent(e.Account.VotePercs[option]))
+	}
+	table.Append(percs)
+	table.Render()
+	fmt.Printf("Blend: %s\n", e.Blend)
+
+	fmt.Println("--- AIRDROP ---")
+	var (
+		acc      = e.Account
+		m        = e.Multipliers
+		unlocked = acc.LiquidAmount.Sub(acc.LockedAmount)
+	)
+	table = tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"", "Amount", "Multiplier", "Airdrop"})
+	table.SetAutoWrapText(false)
+	for _, row := range []struct {
+		name      string
+		amt, mult sdk.Dec
+	}{
+		{"Liquid (unlocked)", unlocked, m.Liquid},
+		{"Rewards", acc.RewardsAmount, m.Liquid},
+		{"Locked", acc.LockedAmount, m.Locked},
+		{"Staked", acc.StakedAmount, m.Staking},
+		{"Unbonding", acc.UnbondingAmount, m.Unbonding},
+	} {
+		table.Append([]string{row.name, amount(row.amt), row.mult.String(), amount(row.amt.Mul(row.mult))})
+	}
+	table.SetFooter([]string{"", "", "Total", amount(e.Airdrop)})
+	table.Render()
+	if e.ICF {
+		fmt.Println("ICF wallet, the airdrop is slashed")
+	}
+}
+
+// voteAmounts returns the amounts of the vote options yes, no, no with veto
+// and abstain of votes.
+func voteAmounts(votes map[govtypes.VoteOption]sdk.Dec) []string {
+	return []string{
+		amount(votes[govtypes.OptionYes]),
+		amount(votes[govtypes.OptionNo]),
+		amount(votes[govtypes.OptionNoWithVeto]),
+		amount(votes[govtypes.OptionAbstain]),
+	}
+}
+
+// amount returns d in the base denom, unlike human it doesn't divide it by a
+// million, because the amounts of a single account can be small.
+func amount(d sdk.Dec) string {
+	return h.Comma(d.TruncateInt64())
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+func TestNewExplanation(t *testing.T) {
+	var (
+		accAddrs = createAccountAddrs(2)
+		valAddrs = createValidatorAddrs(3)
+		voteYes  = govtypes.WeightedVoteOptions{{Option: govtypes.OptionYes, Weight: sdk.OneDec()}}
+		voteNo   = govtypes.WeightedVoteOptions{{Option: govtypes.OptionNo, Weight: sdk.OneDec()}}
+		// valAddrs[0] has 2 tokens per share and votes yes, valAddrs[1] didn't
+		// vote, valAddrs[2] isn't in the active set.
+		valsByAddr = map[string]govtypes.ValidatorGovInfo{
+			valAddrs[0].String(): govtypes.NewValidatorGovInfo(valAddrs[0], sdk.NewInt(2000), sdk.NewDec(1000), sdk.ZeroDec(), voteYes),
+			valAddrs[1].String(): govtypes.NewValidatorGovInfo(valAddrs[1], sdk.NewInt(1000), sdk.NewDec(1000), sdk.ZeroDec(), nil),
+		}
+		delegsByAddr = map[string][]stakingtypes.Delegation{
+			accAddrs[0].String(): {
+				stakingtypes.NewDelegation(accAddrs[0], valAddrs[0], sdk.NewDec(100)),
+				stakingtypes.NewDelegation(accAddrs[0], valAddrs[1], sdk.NewDec(50)),
+				stakingtypes.NewDelegation(accAddrs[0], valAddrs[2], sdk.NewDec(10)),
+			},
+			accAddrs[1].String(): {
+				stakingtypes.NewDelegation(accAddrs[1], valAddrs[0], sdk.NewDec(100)),
+			},
+			// self-delegation of valAddrs[0]
+			sdk.AccAddress(valAddrs[0]).String(): {
+				stakingtypes.NewDelegation(sdk.AccAddress(valAddrs[0]), valAddrs[0], sdk.NewDec(300)),
+			},
+		}
+		balancesByAddr = map[string]sdk.Coins{
+			accAddrs[0].String(): sdk.NewCoins(sdk.NewInt64Coin("uatom", 42)),
+			// tokenized shares of valAddrs[0]
+			accAddrs[1].String(): sdk.NewCoins(sdk.NewInt64Coin(valAddrs[0].String()+"/1", 25)),
+		}
+		votesByAddr = map[string]govtypes.WeightedVoteOptions{
+			accAddrs[1].String():                 voteNo,
+			sdk.AccAddress(valAddrs[0]).String(): voteYes,
+		}
+	)
+
+	t.Run("inherited", func(t *testing.T) {
+		e := newExplanation(accAddrs[0].String(), votesByAddr, valsByAddr, delegsByAddr, balancesByAddr)
+
+		assert.Equal(t, "42uatom", e.Balance.String())
+		assert.Empty(t, e.Vote)
+		require.Len(t, e.Delegations, 3)
+		// inherits valAddrs[0] vote, 100 shares are 200 tokens
+		d := e.Delegations[0]
+		assert.True(t, d.Active)
+		assert.True(t, d.Inherited)
+		assert.Equal(t, voteYes, d.Vote)
+		assert.Equal(t, sdk.NewDec(200).String(), d.Tokens.String())
+		assert.Equal(t, sdk.NewDec(200).String(), d.VotingPower[govtypes.OptionYes].String())
+		// valAddrs[1] didn't vote, no voting power cast
+		d = e.Delegations[1]
+		assert.True(t, d.Active)
+		assert.False(t, d.Inherited)
+		assert.Empty(t, d.Vote)
+		assert.Equal(t, sdk.NewDec(50).String(), d.Tokens.String())
+		// valAddrs[2] is inactive
+		d = e.Delegations[2]
+		assert.False(t, d.Active)
+		assert.True(t, d.Tokens.IsZero())
+		assert.Equal(t, sdk.NewDec(200).String(), e.VotingPower[govtypes.OptionYes].String())
+		assert.True(t, e.VotingPower[govtypes.OptionNo].IsZero())
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		e := newExplanation(accAddrs[1].String(), votesByAddr, valsByAddr, delegsByAddr, balancesByAddr)
+
+		require.Len(t, e.Delegations, 2)
+		d := e.Delegations[0]
+		assert.False(t, d.Inherited)
+		assert.True(t, d.Overrides)
+		assert.False(t, d.ShareTokens)
+		assert.Equal(t, voteNo, d.Vote)
+		assert.Equal(t, voteYes, d.ValidatorVote)
+		// tokenized shares follow the validator vote despite the account vote,
+		// 25 shares are 50 tokens
+		d = e.Delegations[1]
+		assert.True(t, d.ShareTokens)
+		assert.True(t, d.Active)
+		assert.True(t, d.Inherited)
+		assert.Equal(t, valAddrs[0].String(), d.ValidatorAddress)
+		assert.Equal(t, voteYes, d.Vote)
+		assert.Equal(t, sdk.NewDec(50).String(), d.Tokens.String())
+		assert.Equal(t, sdk.NewDec(200).String(), e.VotingPower[govtypes.OptionNo].String())
+		assert.Equal(t, sdk.NewDec(50).String(), e.VotingPower[govtypes.OptionYes].String())
+	})
+
+	t.Run("validator self-delegation", func(t *testing.T) {
+		e := newExplanation(sdk.AccAddress(valAddrs[0]).String(), votesByAddr, valsByAddr, delegsByAddr, balancesByAddr)
+
+		require.Len(t, e.Delegations, 1)
+		d := e.Delegations[0]
+		assert.False(t, d.Inherited)
+		assert.False(t, d.Overrides)
+		assert.Equal(t, sdk.NewDec(600).String(), e.VotingPower[govtypes.OptionYes].String())
+	})
+}
+
+func TestNewAirdropExplanation(t *testing.T) {
+	var (
+		voteYes  = govtypes.WeightedVoteOptions{{Option: govtypes.OptionYes, Weight: sdk.OneDec()}}
+		voteNo   = govtypes.WeightedVoteOptions{{Option: govtypes.OptionNo, Weight: sdk.OneDec()}}
+		accounts = func() []Account {
+			return []Account{
+				{
+					Address:      "yes",
+					LiquidAmount: sdk.NewDec(1),
+					StakedAmount: sdk.NewDec(2),
+					Vote:         voteYes,
+				},
+				{
+					Address:      "no",
+					LiquidAmount: sdk.NewDec(1),
+					StakedAmount: sdk.NewDec(2),
+					Vote:         voteNo,
+				},
+				{
+					Address:         "didntVote",
+					LiquidAmount:    sdk.NewDec(3),
+					StakedAmount:    sdk.NewDec(1),
+					UnbondingAmount: sdk.NewDec(4),
+					LockedAmount:    sdk.NewDec(2),
+					Delegations:     []Delegation{{Amount: sdk.NewDec(1)}},
+				},
+				{
+					Address:      icfWallets[0],
+					LiquidAmount: sdk.NewDec(10),
+					StakedAmount: sdk.ZeroDec(),
+				},
+			}
+		}
+		// yes and no are 50% each, blend = 0.5 + 0.5*4
+		blend          = sdk.NewDecWithPrec(25, 1)
+		halfMultiplier = sdk.NewDecWithPrec(5, 1)
+	)
+
+	e, err := newAirdropExplanation(accounts(), "didntVote", distributionParams{lockedMultiplier: &halfMultiplier})
+
+	require.NoError(t, err)
+	assert.Equal(t, blend.String(), e.Blend.String())
+	assert.Equal(t, sdk.OneDec().String(), e.Account.VotePercs[govtypes.OptionEmpty].String())
+	assert.Equal(t, blend.Mul(malus).String(), e.Multipliers.Staking.String())
+	assert.Equal(t, blend.Mul(malus).String(), e.Multipliers.Liquid.String())
+	assert.Equal(t, blend.Mul(malus).String(), e.Multipliers.Unbonding.String())
+	assert.Equal(t, halfMultiplier.String(), e.Multipliers.Locked.String())
+	assert.False(t, e.ICF)
+	// same airdrop as the distribution
+	res, err := distribution(accounts(), distributionParams{lockedMultiplier: &halfMultiplier})
+	require.NoError(t, err)
+	assert.Equal(t, res["didntVote"].String(), e.Airdrop.String())
+
+	e, err = newAirdropExplanation(accounts(), icfWallets[0], distributionParams{})
+
+	require.NoError(t, err)
+	assert.True(t, e.ICF)
+	assert.True(t, e.Airdrop.IsZero())
+
+	_, err = newAirdropExplanation(accounts(), "unknown", distributionParams{})
+
+	require.EqualError(t, err, "account unknown not found")
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// dataSource locates the snapshot data read by the parse* functions.
+//
+// By default the data is read from the files of the dataset directory, as
+// extracted in SNAPSHOT-EXTRACT.md. When the export files are set, the same
+// data is read straight out of the `gaiad export` JSONs, so no jq extraction
+// is required.
+type dataSource struct {
+	// dir is the dataset directory.
+	dir string
+	// preTallyExport is the export of the block preceding the tally, which
+	// still holds the votes of the proposal.
+	preTallyExport string
+	// tallyExport is the export of the tally block, which holds the
+	// validators, delegations, balances and accounts.
+	tallyExport string
+	// propID filters the votes and the proposal read from the exports.
+	propID uint64
+	// tallyVotes is an optional file containing the votes broadcasted in the
+	// tally block, which are not part of the exports.
+	tallyVotes string
+}
+
+var errKeyNotFound = errors.New("cannot find key")
+
+// exportPath is the location of a dataset file inside the exports.
+type exportPath struct {
+	preTally bool
+	keys     []string
+}
+
+// exportPaths maps the dataset files to the exports, following the jq
+// commands of SNAPSHOT-EXTRACT.md.
+var exportPaths = map[string]exportPath{
+	"votes.json":          {preTally: true, keys: []string{"app_state", "gov", "votes"}},
+	"prop.json":           {keys: []string{"app_state", "gov", "proposals"}},
+	"tally_params.json":   {keys: []string{"app_state", "gov", "tally_params"}},
+	"delegations.json":    {keys: []string{"app_state", "staking", "delegations"}},
+	"validators.json":     {keys: []string{"app_state", "staking", "validators"}},
+	"staking_params.json": {keys: []string{"app_state", "staking", "params"}},
+	"balances.json":       {keys: []string{"app_state", "bank", "balances"}},
+	"supply.json":         {keys: []string{"app_state", "bank", "supply"}},
+	"auth_genesis.json":   {keys: []string{"app_state", "auth"}},
+	"denom_traces.json":   {keys: []string{"app_state", "transfer", "denom_traces"}},
+
+	"tokenize_share_records.json": {keys: []string{"app_state", "staking", "tokenize_share_records"}},
+	"unbonding_delegations.json":  {keys: []string{"app_state", "staking", "unbonding_delegations"}},
+	"distribution_genesis.json":   {keys: []string{"app_state", "distribution"}},
+}
+
+func (s dataSource) fromExport() bool {
+	return s.preTallyExport != "" || s.tallyExport != ""
+}
+
+func (s dataSource) validate() error {
+	if !s.fromExport() {
+		return nil
+	}
+	if s.preTallyExport == "" || s.tallyExport == "" {
+		return fmt.Errorf("both pre-tally and tally exports are required")
+	}
+	if s.propID == 0 {
+		return fmt.Errorf("a proposal id is required to read from exports")
+	}
+	return nil
+}
+
+// has returns true if the dataset file is available.
+func (s dataSource) has(file string) bool {
+	if s.fromExport() {
+		_, ok := exportPaths[file]
+		return ok
+	}
+	_, err := findFile(filepath.Join(s.dir, file))
+	return err == nil
+}
+
+// openOptional is like open, but returns a nil decoder when the dataset file
+// isn't available, whether it's missing from the dataset directory or from
+// the exports.
+func (s dataSource) openOptional(file string) (*json.Decoder, io.Closer, error) {
+	if !s.has(file) {
+		return nil, nil, nil
+	}
+	dec, f, err := s.open(file)
+	if errors.Is(err, errKeyNotFound) {
+		return nil, nil, nil
+	}
+	return dec, f, err
+}
+
+// open returns a decoder positioned at the beginning of the data of the
+// dataset file, either from the dataset directory or from the exports. Files
+// can be compressed, see openFile.
+func (s dataSource) open(file string) (*json.Decoder, io.Closer, error) {
+	if !s.fromExport() {
+		f, err := openFile(filepath.Join(s.dir, file))
+		if err != nil {
+			return nil, nil, err
+		}
+		return json.NewDecoder(f), f, nil
+	}
+	p, ok := exportPaths[file]
+	if !ok {
+		return nil, nil, fmt.Errorf("%s cannot be read from exports", file)
+	}
+	export := s.tallyExport
+	if p.preTally {
+		export = s.preTallyExport
+	}
+	return openExport(export, p.keys...)
+}
+
+// openExport returns a decoder positioned at the value found under the nested
+// keys of the export file.
+func openExport(export string, keys ...string) (*json.Decoder, io.Closer, error) {
+	f, err := openFile(export)
+	if err != nil {
+		return nil, nil, err
+	}
+	dec := json.NewDecoder(f)
+	if err := seekJSON(dec, keys...); err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("%s: %w", export, err)
+	}
+	return dec, f, nil
+}
+
+// seekJSON advances dec to the value found under the nested keys, skipping
+// every other value without decoding it, which keeps the memory low when
+// reading multi-GB exports.
+func seekJSON(dec *json.Decoder, keys ...string) error {
+	for _, key := range keys {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if t != json.Delim('{') {
+			return fmt.Errorf("%w %q: not an object", errKeyNotFound, key)
+		}
+		for {
+			if !dec.More() {
+				return fmt.Errorf("%w %q", errKeyNotFound, key)
+			}
+			t, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if t == key {
+				break
+			}
+			if err := skipJSON(dec); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// skipJSON consumes the next value of dec.
+func skipJSON(dec *json.Decoder) error {
+	depth := 0
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+)
+
+var exportSource = dataSource{
+	preTallyExport: "testdata/export-pre-tally.json",
+	tallyExport:    "testdata/export-tally.json",
+	propID:         848,
+}
+
+func TestParseFromExport(t *testing.T) {
+	var (
+		require = require.New(t)
+		assert  = assert.New(t)
+		src     = exportSource
+		val0    = "cosmosvaloper1srjwwypstmwuf7s77d9whuj060q9xjafenflmz"
+		val1    = "cosmosvaloper1kd5g6r7jjg5q4dp7q4mmp5cd2ayku6dhnzpmn3"
+		acc0    = "cosmos19mmzdrgpjwvwmq3vx6zqnrqdfxt4grvkvvxlps"
+		acc1    = "cosmos1j7skdhh9raxdmfhmcy2gxz8hgn0jnhfmujjsfe"
+	)
+	require.NoError(src.validate())
+
+	votesByAddr, _, err := parseVotesByAddr(src)
+	require.NoError(err)
+	assert.Len(votesByAddr, 2, "votes of prop 847 must be filtered out")
+	assert.Equal(govtypes.OptionNo, votesByAddr[acc1][0].Option)
+
+	valsByAddr, err := parseValidatorsByAddr(src, votesByAddr)
+	require.NoError(err)
+	assert.Len(valsByAddr, 2, "active set is limited to max_validators")
+	if assert.Contains(valsByAddr, val0) {
+		assert.Equal(govtypes.OptionYes, valsByAddr[val0].Vote[0].Option)
+	}
+	assert.Contains(valsByAddr, val1)
+
+	delegsByAddr, err := parseDelegationsByAddr(src)
+	require.NoError(err)
+	assert.Len(delegsByAddr, 4)
+	assert.Len(delegsByAddr[acc0], 2)
+
+	balancesByAddr, err := parseBalancesByAddr(src, map[string]sdk.Dec{"uatom": sdk.OneDec()})
+	require.NoError(err)
+	assert.Equal(sdk.NewCoins(sdk.NewInt64Coin("uatom", 100)), balancesByAddr[acc0])
+
+	denomTraces, err := parseDenomTraces(src)
+	require.NoError(err)
+	denomWeights, err := parseDenomWeights("uatom,*/uatom=0.5", denomTraces)
+	require.NoError(err)
+	balancesByAddr, err = parseBalancesByAddr(src, denomWeights)
+	require.NoError(err)
+	assert.Equal(sdk.NewDec(220).String(), liquidAmount(balancesByAddr[acc1], denomWeights).String())
+
+	accountTypesByAddr, vestingAccsByAddr, err := parseAuthAccounts(src)
+	require.NoError(err)
+	assert.Equal("/cosmos.auth.v1beta1.BaseAccount", accountTypesByAddr[acc0])
+	assert.Len(vestingAccsByAddr, 1)
+
+	shareRecords, err := parseTokenizeShareRecords(src)
+	require.NoError(err)
+	unbondingsByAddr, err := parseUnbondingsByAddr(src)
+	require.NoError(err)
+	assert.Len(unbondingsByAddr, 1)
+	// sum of the entries balances, slashes excluded
+	assert.Equal(sdk.NewInt(3_700).String(), unbondingsByAddr[acc1].String())
+
+	prop, err := parseProp(src)
+	require.NoError(err)
+	assert.Equal(uint64(848), prop.ProposalId)
+
+	rewardsByAddr, err := parseRewardsByAddr(src, delegsByAddr, shareRecords)
+	require.NoError(err)
+	// acc2 rewards are truncated to zero
+	assert.Len(rewardsByAddr, 3)
+	// 10000 from val0, 20000 before and 10000 after the val1 slash
+	assert.Equal("40000uatom", rewardsByAddr[acc0].String())
+	// 40000 before and 20000 after the val1 slash
+	assert.Equal("60000uatom", rewardsByAddr[acc1].String())
+
+	accounts := getAccounts(delegsByAddr, votesByAddr, valsByAddr, balancesByAddr, denomWeights,
+		accountTypesByAddr, shareRecords, unbondingsByAddr, vestingAccsByAddr, prop.VotingEndTime, rewardsByAddr)
+	// sorted by address, and delegations by validator address
+	var addrs []string
+	for _, acc := range accounts {
+		addrs = append(addrs, acc.Address)
+	}
+	assert.Equal([]string{
+		acc0, "cosmos1dyy7w6zwujnxyc32dxv5z8w8kpthtnwxr5lqxx", acc1, "cosmos1srjwwypstmwuf7s77d9whuj060q9xjafu8a2h3",
+	}, addrs)
+	if assert.Len(accounts[0].Delegations, 2) {
+		assert.Equal(val1, accounts[0].Delegations[0].ValidatorAddress)
+		assert.Equal(val0, accounts[0].Delegations[1].ValidatorAddress)
+	}
+	// same output across runs
+	bz, err := json.MarshalIndent(accounts, "", "  ")
+	require.NoError(err)
+	for i := 0; i < 10; i++ {
+		again := getAccounts(delegsByAddr, votesByAddr, valsByAddr, balancesByAddr, denomWeights,
+			accountTypesByAddr, shareRecords, unbondingsByAddr, vestingAccsByAddr, prop.VotingEndTime, rewardsByAddr)
+		againBz, err := json.MarshalIndent(again, "", "  ")
+		require.NoError(err)
+		require.Equal(string(bz), string(againBz))
+	}
+	for _, acc := range accounts {
+		if acc.Address == acc1 {
+			assert.Equal(sdk.NewDec(3_700).String(), acc.UnbondingAmount.String())
+			assert.Equal(sdk.NewDec(60_000).String(), acc.RewardsAmount.String())
+		}
+		if acc.Address == "cosmos1dyy7w6zwujnxyc32dxv5z8w8kpthtnwxr5lqxx" {
+			// tokenized shares of val0 inherit its vote
+			assert.Equal(sdk.NewDec(500_000).String(), acc.StakedAmount.String())
+			if assert.Len(acc.Delegations, 1) {
+				assert.Equal(val0, acc.Delegations[0].ValidatorAddress)
+				assert.Equal(govtypes.OptionYes, acc.Delegations[0].Vote[0].Option)
+			}
+			// 282 days of a 365 days continuous vesting have elapsed
+			if assert.NotNil(acc.Vesting) {
+				assert.Equal("773uatom", acc.Vesting.VestedCoins.String())
+				assert.Equal("227uatom", acc.Vesting.LockedCoins.String())
+			}
+			assert.Equal(sdk.NewDec(227).String(), acc.LockedAmount.String())
+		}
+	}
+
+	res := tally(votesByAddr, valsByAddr, delegsByAddr)
+	tallyResult := govtypes.NewTallyResultFromMap(res.Results)
+	assert.Equal(prop.FinalTallyResult.String(), tallyResult.String())
+	assert.Equal(sdk.NewDec(6_000_000).String(), res.TotalVotingPower.String())
+
+	tallyParams, err := parseTallyParams(src)
+	require.NoError(err)
+	require.NotNil(tallyParams)
+	assert.Equal("0.334000000000000000", tallyParams.VetoThreshold.String())
+	totalBonded, err := parseTotalBondedTokens(src)
+	require.NoError(err)
+	assert.Equal(sdk.NewInt(8_000_000).String(), totalBonded.String())
+	// the bonded pool holds the tokens of the bonded validators
+	valsTokens, err := parseBondedValidatorsTokens(src)
+	require.NoError(err)
+	assert.Equal(totalBonded.String(), valsTokens.String())
+	outcome := newTallyOutcome(res, totalBonded, *tallyParams)
+	assert.Equal("0.750000000000000000", outcome.Participation.String())
+	assert.True(outcome.QuorumReached)
+	assert.True(outcome.Passes)
+	assert.Equal(govtypes.StatusPassed, prop.Status)
+}
+
+func TestDataSourceValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		src         dataSource
+		expectedErr string
+	}{
+		{
+			name: "dataset directory",
+			src:  dataSource{dir: "data"},
+		},
+		{
+			name: "exports",
+			src:  exportSource,
+		},
+		{
+			name:        "missing tally export",
+			src:         dataSource{preTallyExport: "pre.json", propID: 1},
+			expectedErr: "both pre-tally and tally exports are required",
+		},
+		{
+			name:        "missing proposal id",
+			src:         dataSource{preTallyExport: "pre.json", tallyExport: "tally.json"},
+			expectedErr: "a proposal id is required to read from exports",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.src.validate()
+
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestParseFromExportWithoutOptionalFiles(t *testing.T) {
+	var (
+		require = require.New(t)
+		assert  = assert.New(t)
+		src     = exportSource
+	)
+	// Remove the optional keys from the tally export
+	bz, err := os.ReadFile(src.tallyExport)
+	require.NoError(err)
+	var export map[string]any
+	require.NoError(json.Unmarshal(bz, &export))
+	appState := export["app_state"].(map[string]any)
+	delete(appState["staking"].(map[string]any), "tokenize_share_records")
+	delete(appState["staking"].(map[string]any), "unbonding_delegations")
+	delete(appState, "distribution")
+	bz, err = json.Marshal(export)
+	require.NoError(err)
+	src.tallyExport = filepath.Join(t.TempDir(), "export-tally.json")
+	require.NoError(os.WriteFile(src.tallyExport, bz, 0o666))
+
+	shareRecords, err := parseTokenizeShareRecords(src)
+	require.NoError(err)
+	assert.Empty(shareRecords)
+	unbondingsByAddr, err := parseUnbondingsByAddr(src)
+	require.NoError(err)
+	assert.Empty(unbondingsByAddr)
+	rewardsByAddr, err := parseRewardsByAddr(src, nil, nil)
+	require.NoError(err)
+	assert.Empty(rewardsByAddr)
+}
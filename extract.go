@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// extractedFile is a dataset file written by extract.
+type extractedFile struct {
+	Path string
+	MD5  []byte
+	// Skipped is set when the file is optional and not in the exports, in
+	// which case it isn't written.
+	Skipped error
+}
+
+// extract reproduces the SNAPSHOT-EXTRACT.md pipeline: it reads the exports
+// of src and writes the dataset files consumed by the other commands into
+// src.dir. It returns the files with their md5, and the votes overridden
+// while merging the votes.
+func extract(src dataSource) ([]extractedFile, []voteOverride, error) {
+	if err := os.MkdirAll(src.dir, 0o777); err != nil {
+		return nil, nil, err
+	}
+	var overrides []voteOverride
+	extractors := []struct {
+		file string
+		fn   func(io.Writer) error
+		// optional files are skipped when they are not in the exports.
+		optional bool
+	}{
+		{file: "votes.json", fn: func(w io.Writer) (err error) {
+			overrides, err = extractVotes(w, src)
+			return err
+		}},
+		{file: "prop.json", fn: func(w io.Writer) error { return extractProp(w, src) }},
+		{file: "tally_params.json", fn: func(w io.Writer) error { return extractObject(w, src, "tally_params.json") }},
+		{file: "delegations.json", fn: func(w io.Writer) error { return extractArray(w, src, "delegations.json") }},
+		{file: "validators.json", fn: func(w io.Writer) error { return extractArray(w, src, "validators.json") }},
+		{file: "staking_params.json", fn: func(w io.Writer) error { return extractObject(w, src, "staking_params.json") }},
+		{file: "balances.json", fn: func(w io.Writer) error { return extractArray(w, src, "balances.json") }},
+		{file: "auth_genesis.json", fn: func(w io.Writer) error { return extractObject(w, src, "auth_genesis.json") }},
+		{
+			file:     "denom_traces.json",
+			fn:       func(w io.Writer) error { return extractArray(w, src, "denom_traces.json") },
+			optional: true,
+		},
+		{
+			file:     "tokenize_share_records.json",
+			fn:       func(w io.Writer) error { return extractArray(w, src, "tokenize_share_records.json") },
+			optional: true,
+		},
+		{
+			file:     "unbonding_delegations.json",
+			fn:       func(w io.Writer) error { return extractArray(w, src, "unbonding_delegations.json") },
+			optional: true,
+		},
+		{
+			file:     "distribution_genesis.json",
+			fn:       func(w io.Writer) error { return extractObject(w, src, "distribution_genesis.json") },
+			optional: true,
+		},
+		{
+			file:     "supply.json",
+			fn:       func(w io.Writer) error { return extractArray(w, src, "supply.json") },
+			optional: true,
+		},
+	}
+	var files []extractedFile
+	for _, e := range extractors {
+		path := filepath.Join(src.dir, e.file)
+		sum, err := writeFileMD5(path, e.fn)
+		if e.optional && errors.Is(err, errKeyNotFound) {
+			os.Remove(path)
+			files = append(files, extractedFile{Path: path, Skipped: err})
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("extract %s: %w", e.file, err)
+		}
+		files = append(files, extractedFile{Path: path, MD5: sum})
+	}
+	return files, overrides, nil
+}
+
+// writeFileMD5 writes the file with fn and returns its md5.
+func writeFileMD5(path string, fn func(io.Writer) error) ([]byte, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var (
+		hash = md5.New()
+		w    = bufio.NewWriter(io.MultiWriter(f, hash))
+	)
+	if err := fn(w); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), f.Close()
+}
+
+// extractVotes writes the merged votes of the proposal, and returns the
+// overridden votes, see mergeVotes.
+func extractVotes(w io.Writer, src dataSource) ([]voteOverride, error) {
+	votes, overrides, err := mergeVotes(src.voteSources(), src.propID)
+	if err != nil {
+		return nil, err
+	}
+	aw := jsonArrayWriter{w: w}
+	for _, vote := range votes {
+		if err := aw.write(vote.raw); err != nil {
+			return nil, err
+		}
+	}
+	return overrides, aw.close()
+}
+
+// extractProp writes the proposal matching src.propID.
+func extractProp(w io.Writer, src dataSource) error {
+	dec, f, err := src.open("prop.json")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var prop json.RawMessage
+	err = forEachRaw(dec, func(raw json.RawMessage) error {
+		var p struct {
+			ProposalID uint64 `json:"proposal_id,string"`
+		}
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return err
+		}
+		if p.ProposalID == src.propID {
+			prop = raw
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if prop == nil {
+		return fmt.Errorf("proposal %d not found", src.propID)
+	}
+	return writeIndentJSON(w, prop)
+}
+
+// extractArray copies the array of the dataset file, one element at a time.
+func extractArray(w io.Writer, src dataSource, file string) error {
+	dec, f, err := src.open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	aw := jsonArrayWriter{w: w}
+	if err := forEachRaw(dec, aw.write); err != nil {
+		return err
+	}
+	return aw.close()
+}
+
+// extractObject copies the object of the dataset file.
+func extractObject(w io.Writer, src dataSource, file string) error {
+	dec, f, err := src.open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	return writeIndentJSON(w, raw)
+}
+
+// forEachRaw calls fn for each element of the array dec is positioned at.
+func forEachRaw(dec *json.Decoder, fn func(json.RawMessage) error) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if t != json.Delim('[') {
+		return fmt.Errorf("expected array, got %v", t)
+	}
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		if err := fn(raw); err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token()
+	return err
+}
+
+// writeIndentJSON writes raw indented like jq does.
+func writeIndentJSON(w io.Writer, raw json.RawMessage) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// jsonArrayWriter writes a JSON array one element at a time, indented like jq
+// does.
+type jsonArrayWriter struct {
+	w   io.Writer
+	buf bytes.Buffer
+	n   int
+}
+
+func (a *jsonArrayWriter) write(raw json.RawMessage) error {
+	a.buf.Reset()
+	if a.n == 0 {
+		a.buf.WriteString("[\n  ")
+	} else {
+		a.buf.WriteString(",\n  ")
+	}
+	if err := json.Indent(&a.buf, raw, "  ", "  "); err != nil {
+		return err
+	}
+	a.n++
+	_, err := a.w.Write(a.buf.Bytes())
+	return err
+}
+
+func (a *jsonArrayWriter) close() error {
+	end := "\n]\n"
+	if a.n == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(a.w, end)
+	return err
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+)
+
+func TestExtract(t *testing.T) {
+	var (
+		require = require.New(t)
+		assert  = assert.New(t)
+		src     = exportSource
+	)
+	src.dir = t.TempDir()
+	src.tallyVotes = "testdata/tally-votes.json"
+
+	files, overrides, err := extract(src)
+
+	require.NoError(err)
+	// the tally block vote overrides the export vote
+	assert.Len(overrides, 1)
+	// the optional files are all in the exports
+	require.Len(files, 13)
+	for _, f := range files {
+		assert.NoError(f.Skipped)
+		assert.Len(f.MD5, 16, f.Path)
+	}
+	for _, file := range []string{
+		"votes.json", "prop.json", "delegations.json", "validators.json",
+		"staking_params.json", "balances.json", "auth_genesis.json",
+		"denom_traces.json", "tokenize_share_records.json",
+	} {
+		assert.FileExists(filepath.Join(src.dir, file))
+	}
+	// Read back the extracted dataset
+	dirSrc := dataSource{dir: src.dir}
+	votesByAddr, dirOverrides, err := parseVotesByAddr(dirSrc)
+	require.NoError(err)
+	assert.Equal(map[string]govtypes.VoteOption{
+		"cosmos1srjwwypstmwuf7s77d9whuj060q9xjafu8a2h3": govtypes.OptionYes,
+		// last vote is kept
+		"cosmos1j7skdhh9raxdmfhmcy2gxz8hgn0jnhfmujjsfe": govtypes.OptionAbstain,
+		// vote from the tally block
+		"cosmos1dyy7w6zwujnxyc32dxv5z8w8kpthtnwxr5lqxx": govtypes.OptionNoWithVeto,
+	}, firstOptions(votesByAddr))
+	assert.Empty(dirOverrides, "extracted votes must be already merged")
+	valsByAddr, err := parseValidatorsByAddr(dirSrc, votesByAddr)
+	require.NoError(err)
+	expectedValsByAddr, err := parseValidatorsByAddr(src, votesByAddr)
+	require.NoError(err)
+	assert.Equal(expectedValsByAddr, valsByAddr)
+	prop, err := parseProp(src)
+	require.NoError(err)
+	dirProp, err := parseProp(dirSrc)
+	require.NoError(err)
+	assert.Equal(prop, dirProp)
+}
+
+func TestExtractPropNotFound(t *testing.T) {
+	src := exportSource
+	src.dir = t.TempDir()
+	src.propID = 1
+
+	_, _, err := extract(src)
+
+	require.EqualError(t, err, "extract prop.json: proposal 1 not found")
+	_, err = os.Stat(filepath.Join(src.dir, "votes.json"))
+	require.NoError(t, err)
+}
+
+func firstOptions(votesByAddr map[string]govtypes.WeightedVoteOptions) map[string]govtypes.VoteOption {
+	m := make(map[string]govtypes.VoteOption)
+	for addr, vote := range votesByAddr {
+		m[addr] = vote[0].Option
+	}
+	return m
+}
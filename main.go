@@ -2,33 +2,210 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	h "github.com/dustin/go-humanize"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
 )
 
-var commands = []string{"tally", "accounts", "genesis", "autostaking", "distribution"}
+var commands = []string{"tally", "simulate", "participation", "accounts", "genesis", "autostaking", "distribution", "explain", "extract", "manifest"}
 
 func main() {
-	if len(os.Args) != 3 || !slices.Contains(commands, os.Args[1]) {
-		fmt.Fprintf(os.Stderr, "Usage:\n%s [%s] [datapath]\n",
-			filepath.Base(os.Args[0]), strings.Join(commands, "|"))
-		os.Exit(1)
+	if len(os.Args) < 3 || !slices.Contains(commands, os.Args[1]) {
+		usage(nil)
+	}
+	var (
+		command = os.Args[1]
+		flags   = flag.NewFlagSet(command, flag.ExitOnError)
+		src     dataSource
+		denoms  string
+		// tally and simulate flags
+		ruleName  string
+		threshold string
+		// tally flags
+		validatorsReport bool
+		validatorsSort   string
+		tallyFormat      string
+		verify           bool
+		tolerance        string
+		propsList        string
+		propIDs          []uint64
+		concentration    bool
+		topN             int
+		maxValidators    uint
+		// simulate flags
+		overridesFile string
+		// manifest flags
+		height int64
+		// distribution and explain flags
+		unbondingMultiplier string
+		lockedMultiplier    string
+		// explain account
+		address string
+	)
+	flags.StringVar(&src.preTallyExport, "pre-tally-export", "",
+		"read votes from this `file`, a gaiad export of the block preceding the tally, instead of the datapath files")
+	flags.StringVar(&src.tallyExport, "tally-export", "",
+		"read validators, delegations, prop, balances and accounts from this `file`, a gaiad export of the tally block, instead of the datapath files")
+	flags.Uint64Var(&src.propID, "prop", 0, "proposal id, required with exports")
+	flags.StringVar(&src.tallyVotes, "tally-votes", "",
+		"optional JSON `file` containing the votes broadcasted in the tally block, merged after the other votes")
+	flags.StringVar(&denoms, "denoms", defaultDenoms,
+		"comma separated `list` of denom=weight counted in the balances, denom can be native, ibc/HASH, an IBC denom path, or */BASE_DENOM for all IBC denoms of that base")
+	if command == "tally" || command == "simulate" {
+		flags.StringVar(&ruleName, "rule", "hub",
+			"tally rule: hub (Cosmos Hub v1beta1, validator votes inherited by silent delegators) or no-inheritance (only cast votes count)")
+		flags.StringVar(&threshold, "threshold", "",
+			"pass threshold of the tally rule, like 0.667 for a supermajority, defaults to the gov tally params threshold")
+	}
+	if command == "distribution" || command == "explain" {
+		flags.StringVar(&unbondingMultiplier, "unbonding-multiplier", "",
+			"multiplier of the tokens in the unbonding period, defaults to the liquid amount multiplier")
+		flags.StringVar(&lockedMultiplier, "locked-multiplier", "",
+			"multiplier of the liquid tokens still locked by a vesting schedule, defaults to the liquid amount multiplier")
+	}
+	switch command {
+	case "tally":
+		flags.BoolVar(&validatorsReport, "validators", false,
+			"print the per-validator breakdown of the tally, and write it in PATH/validators_tally.json")
+		flags.StringVar(&validatorsSort, "sort", "tokens",
+			"sort of the per-validator breakdown: tokens, power, deductions, overrides or address")
+		flags.StringVar(&tallyFormat, "format", "table",
+			"output format of the tally result: table, json, csv or markdown, other messages go to stderr when not table")
+		flags.BoolVar(&verify, "verify", false,
+			"exit with a non-zero code if the computed tally differs from the prop final tally by more than the tolerance")
+		flags.StringVar(&tolerance, "tolerance", "0",
+			"maximum difference per vote option tolerated by -verify, in the base denom")
+		flags.BoolVar(&concentration, "concentration", false,
+			"print the concentration of the voting power of each vote option: Nakamoto coefficients, Gini and top N share")
+		flags.IntVar(&topN, "top", 10, "number of the largest voters of the top N share of -concentration")
+		flags.UintVar(&maxValidators, "max-validators", 0,
+			"tally again with an active set of this size, computed from validators.json, and compare it to the actual tally")
+		flags.StringVar(&propsList, "props", "",
+			"comma separated `list` of proposal ids or ranges like 848,850-860, tallied from the exports in one pass instead of -prop")
+	case "simulate":
+		flags.StringVar(&overridesFile, "overrides", "",
+			"JSON `file` of the vote overrides, like {\"votes\": {\"ADDRESS\": \"no\", \"ADDRESS\": null}, \"non_voters\": \"yes=0.5,no=0.5\"}")
+	case "manifest":
+		flags.Int64Var(&height, "height", 0, "height of the snapshot the dataset was extracted from")
+	}
+	flags.Usage = func() { usage(flags) }
+	flags.Parse(os.Args[2:])
+	// explain takes the address of the account before the datapath
+	numArgs := 1
+	if command == "explain" {
+		numArgs = 2
+	}
+	if flags.NArg() != numArgs {
+		usage(flags)
+	}
+	if propsList != "" {
+		var err error
+		propIDs, err = parseProposalIDs(propsList)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			usage(flags)
+		}
+		if src.preTallyExport == "" || src.tallyExport == "" || src.propID != 0 {
+			fmt.Fprintln(os.Stderr, "-props requires the pre-tally and tally exports, and replaces -prop")
+			usage(flags)
+		}
+		if tallyFormat != "table" || validatorsReport || concentration || maxValidators > 0 {
+			fmt.Fprintln(os.Stderr, "-format, -validators, -concentration and -max-validators are not supported with -props")
+			usage(flags)
+		}
+	} else if err := src.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		usage(flags)
+	}
+	if _, ok := validatorSorts[validatorsSort]; command == "tally" && !ok {
+		fmt.Fprintf(os.Stderr, "unknown validators sort %q\n", validatorsSort)
+		usage(flags)
+	}
+	if command == "tally" && topN < 1 {
+		fmt.Fprintf(os.Stderr, "invalid top %d\n", topN)
+		usage(flags)
+	}
+	if maxValidators > math.MaxUint32 {
+		fmt.Fprintf(os.Stderr, "invalid max validators %d, it must be at most %d\n", maxValidators, uint32(math.MaxUint32))
+		usage(flags)
+	}
+	if command == "tally" && !slices.Contains(tallyFormats, tallyFormat) {
+		fmt.Fprintf(os.Stderr, "unknown tally format %q\n", tallyFormat)
+		usage(flags)
 	}
+	toleranceInt, ok := sdk.NewIntFromString(tolerance)
+	if command == "tally" && (!ok || toleranceInt.IsNegative()) {
+		fmt.Fprintf(os.Stderr, "invalid tolerance %q\n", tolerance)
+		usage(flags)
+	}
+	if _, ok := tallyRules[ruleName]; (command == "tally" || command == "simulate") && !ok {
+		fmt.Fprintf(os.Stderr, "unknown tally rule %q, available rules: %s\n", ruleName, tallyRuleNames())
+		usage(flags)
+	}
+	if verify && (ruleName != "hub" || threshold != "") {
+		fmt.Fprintln(os.Stderr, "-verify compares the tally with the chain, it requires the hub rule without -threshold")
+		usage(flags)
+	}
+	if command == "simulate" && overridesFile == "" {
+		fmt.Fprintln(os.Stderr, "simulate requires the -overrides file")
+		usage(flags)
+	}
+	if command == "explain" {
+		address = flags.Arg(0)
+		if _, err := sdk.AccAddressFromBech32(address); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid address %q: %v\n", address, err)
+			usage(flags)
+		}
+	}
+	if command == "extract" && !src.fromExport() {
+		fmt.Fprintln(os.Stderr, "extract requires the pre-tally and tally exports")
+		usage(flags)
+	}
+	src.dir = flags.Arg(numArgs - 1)
 
 	var (
-		command         = os.Args[1]
-		datapath        = os.Args[2]
+		datapath        = src.dir
 		accountsFile    = filepath.Join(datapath, "accounts.json")
 		bankGenesisFile = filepath.Join(datapath, "bank.genesis")
 	)
 
+	var out io.Writer = os.Stdout
+	if command == "tally" && tallyFormat != "table" {
+		// Keep stdout for the formatted tally result
+		out = os.Stderr
+	}
+
+	// Verify the dataset before reading it. genesis and distribution only read
+	// accounts.json, the other commands read the exports instead of the
+	// dataset files when they are given.
+	if command == "genesis" || command == "distribution" ||
+		(!src.fromExport() && command != "extract" && command != "manifest" && command != "autostaking") {
+		var skipped []string
+		if command == "accounts" {
+			// Written by the command
+			skipped = append(skipped, "accounts.json")
+		}
+		verified, err := verifyManifest(datapath, src.propID, skipped...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Dataset verification failed: %v\n", err)
+			os.Exit(1)
+		}
+		if verified {
+			fmt.Fprintf(out, "Dataset verified against %s\n", filepath.Join(datapath, manifestFile))
+		}
+	}
+
 	switch command {
 	case "genesis":
 		accounts, err := parseAccounts(accountsFile)
@@ -51,7 +228,11 @@ func main() {
 		if err != nil {
 			panic(err)
 		}
-		res, err := distribution(accounts)
+		params, err := newDistributionParams(unbondingMultiplier, lockedMultiplier)
+		if err != nil {
+			panic(err)
+		}
+		res, err := distribution(accounts, params)
 		if err != nil {
 			panic(err)
 		}
@@ -64,22 +245,86 @@ func main() {
 			panic(err)
 		}
 		os.Exit(0)
+	case "extract":
+		files, overrides, err := extract(src)
+		if err != nil {
+			panic(err)
+		}
+		if len(overrides) > 0 {
+			fmt.Printf("%d overridden votes:\n", len(overrides))
+			for _, o := range overrides {
+				fmt.Println(o)
+			}
+		}
+		for _, f := range files {
+			if f.Skipped != nil {
+				fmt.Printf("%s skipped: %v\n", f.Path, f.Skipped)
+				continue
+			}
+			fmt.Printf("%x  %s\n", f.MD5, f.Path)
+		}
+		os.Exit(0)
+	case "manifest":
+		manifestPath, err := writeManifest(datapath, src.propID, height)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%s file created.\n", manifestPath)
+		os.Exit(0)
+	}
+
+	if len(propIDs) > 0 {
+		rule := newTallyRuleOrExit(out, ruleName, threshold, tallyParamsOrDefault(out, src))
+		reports, err := batchTally(src, propIDs, rule, toleranceInt)
+		if err != nil {
+			panic(err)
+		}
+		printPropReports(reports)
+		bz, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			panic(err)
+		}
+		reportFile := filepath.Join(datapath, "tally_report.json")
+		if err := os.WriteFile(reportFile, bz, 0o666); err != nil {
+			panic(err)
+		}
+		fmt.Printf("%s file created.\n", reportFile)
+		if verify {
+			failed := false
+			for _, r := range reports {
+				for _, m := range r.Mismatches {
+					fmt.Fprintf(os.Stderr, "Tally verification failed for proposal %d: %s\n", r.ProposalID, m)
+					failed = true
+				}
+			}
+			if failed {
+				os.Exit(1)
+			}
+			fmt.Printf("Tallies verified against the props final tally, tolerance %s\n", toleranceInt)
+		}
+		os.Exit(0)
 	}
 
 	//-----------------------------------------
 	// Read data from files
 
-	votesByAddr, err := parseVotesByAddr(datapath)
+	votesByAddr, voteOverrides, err := parseVotesByAddr(src)
 	if err != nil {
 		panic(err)
 	}
-	fmt.Printf("%s votes\n", h.Comma(int64(len(votesByAddr))))
-	valsByAddr, err := parseValidatorsByAddr(datapath, votesByAddr)
+	fmt.Fprintf(out, "%s votes\n", h.Comma(int64(len(votesByAddr))))
+	if len(voteOverrides) > 0 {
+		fmt.Fprintf(out, "%d overridden votes:\n", len(voteOverrides))
+		for _, o := range voteOverrides {
+			fmt.Fprintln(out, o)
+		}
+	}
+	valsByAddr, err := parseValidatorsByAddr(src, votesByAddr)
 	if err != nil {
 		panic(err)
 	}
-	fmt.Printf("%d validators\n", len(valsByAddr))
-	delegsByAddr, err := parseDelegationsByAddr(datapath)
+	fmt.Fprintf(out, "%d validators\n", len(valsByAddr))
+	delegsByAddr, err := parseDelegationsByAddr(src)
 	if err != nil {
 		panic(err)
 	}
@@ -87,28 +332,179 @@ func main() {
 	for _, d := range delegsByAddr {
 		numDeleg += len(d)
 	}
-	fmt.Printf("%s delegations for %s delegators\n", h.Comma(int64(numDeleg)),
+	fmt.Fprintf(out, "%s delegations for %s delegators\n", h.Comma(int64(numDeleg)),
 		h.Comma(int64(len(delegsByAddr))))
-	balancesByAddr, err := parseBalancesByAddr(datapath, "uatom")
+	denomTraces, err := parseDenomTraces(src)
+	if err != nil {
+		panic(err)
+	}
+	denomWeights, err := parseDenomWeights(denoms, denomTraces)
+	if err != nil {
+		panic(err)
+	}
+	balancesByAddr, err := parseBalancesByAddr(src, denomWeights)
 	if err != nil {
 		panic(err)
 	}
-	fmt.Printf("%s account balances\n", h.Comma(int64(len(balancesByAddr))))
+	fmt.Fprintf(out, "%s account balances\n", h.Comma(int64(len(balancesByAddr))))
 
 	switch command {
 	case "tally":
-		results, totalVotingPower := tally(votesByAddr, valsByAddr, delegsByAddr)
+		rule := newTallyRuleOrExit(out, ruleName, threshold, tallyParamsOrDefault(out, src))
+		totalBonded, err := parseTotalBondedTokens(src)
+		if err != nil {
+			panic(err)
+		}
+		prop, err := parseProp(src)
+		if err != nil {
+			panic(err)
+		}
+		res := rule.tally(votesByAddr, valsByAddr, delegsByAddr)
 		// Optionnaly print and compare tally with prop data
-		printTallyResults(results, totalVotingPower, parseProp(datapath))
+		printTallyResults(out, res, prop)
+		if concentration {
+			printConcentrations(out, newConcentrations(res, topN))
+		}
+		if tallyFormat != "table" {
+			if err := writeTallyRows(os.Stdout, newTallyRows(res, prop), tallyFormat); err != nil {
+				panic(err)
+			}
+		}
+		printTallyOutcome(out, rule.outcome(res, totalBonded), rule.tallyParams(), prop)
+
+		if maxValidators > 0 {
+			resizedValsByAddr, err := parseResizedValidatorsByAddr(src, votesByAddr, uint32(maxValidators))
+			if err != nil {
+				panic(err)
+			}
+			fmt.Fprintf(out, "Active set resized to %d validators\n", len(resizedValsByAddr))
+			resized := rule.tally(votesByAddr, resizedValsByAddr, delegsByAddr)
+			printTallyComparison(out, "ACTIVE SET RESIZE",
+				fmt.Sprintf("Actual %d validators", len(valsByAddr)),
+				fmt.Sprintf("Resized %d validators", len(resizedValsByAddr)),
+				res, resized,
+				rule.outcome(res, totalBonded),
+				rule.outcome(resized, totalBondedTokens(resizedValsByAddr)))
+			printActiveSetChange(out, newActiveSetChange(valsByAddr, resizedValsByAddr, votesByAddr, delegsByAddr))
+		}
+
+		if validatorsReport {
+			vals, err := sortedValidators(res, validatorsSort)
+			if err != nil {
+				panic(err)
+			}
+			printValidatorTallies(out, vals)
+			bz, err := json.MarshalIndent(vals, "", "  ")
+			if err != nil {
+				panic(err)
+			}
+			validatorsFile := filepath.Join(datapath, "validators_tally.json")
+			if err := os.WriteFile(validatorsFile, bz, 0o666); err != nil {
+				panic(err)
+			}
+			fmt.Fprintf(out, "%s file created.\n", validatorsFile)
+		}
+
+		if verify {
+			mismatches := verifyTally(res, prop, toleranceInt)
+			if len(mismatches) > 0 {
+				fmt.Fprintf(os.Stderr, "Tally verification failed, tolerance %s:\n", toleranceInt)
+				for _, m := range mismatches {
+					fmt.Fprintln(os.Stderr, m)
+				}
+				os.Exit(1)
+			}
+			fmt.Fprintf(out, "Tally verified against the prop final tally, tolerance %s\n", toleranceInt)
+		}
+
+	case "simulate":
+		overrides, err := parseVoteOverrides(overridesFile)
+		if err != nil {
+			panic(err)
+		}
+		rule := newTallyRuleOrExit(out, ruleName, threshold, tallyParamsOrDefault(out, src))
+		totalBonded, err := parseTotalBondedTokens(src)
+		if err != nil {
+			panic(err)
+		}
+		simVotesByAddr, simValsByAddr, err := applyVoteOverrides(overrides, votesByAddr, valsByAddr, delegsByAddr)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Fprintf(out, "%s simulated votes\n", h.Comma(int64(len(simVotesByAddr))))
+		baseline := rule.tally(votesByAddr, valsByAddr, delegsByAddr)
+		simulated := rule.tally(simVotesByAddr, simValsByAddr, delegsByAddr)
+		printSimulation(out, baseline, simulated,
+			rule.outcome(baseline, totalBonded),
+			rule.outcome(simulated, totalBonded))
+
+	case "participation":
+		totalBonded, err := parseTotalBondedTokens(src)
+		if err != nil {
+			panic(err)
+		}
+		totalSupply, err := parseBondDenomSupply(src)
+		if err != nil {
+			panic(err)
+		}
+		if totalSupply == nil {
+			fmt.Println("WARNING: supply.json or staking_params.json not found, the turnout of supply is not computed")
+		}
+		p := newParticipation(votesByAddr, valsByAddr, delegsByAddr, totalBonded, totalSupply)
+		printParticipation(p)
+		bz, err := json.MarshalIndent(p, "", "  ")
+		if err != nil {
+			panic(err)
+		}
+		participationFile := filepath.Join(datapath, "participation.json")
+		if err := os.WriteFile(participationFile, bz, 0o666); err != nil {
+			panic(err)
+		}
+		fmt.Printf("%s file created.\n", participationFile)
 
 	case "accounts":
-		accountTypesByAddr, err := parseAccountTypesPerAddr(datapath)
+		accountTypesByAddr, vestingAccsByAddr, err := parseAuthAccounts(src)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%s accounts, %s vesting accounts\n", h.Comma(int64(len(accountTypesByAddr))),
+			h.Comma(int64(len(vestingAccsByAddr))))
+
+		shareRecords, err := parseTokenizeShareRecords(src)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%s tokenize share records\n", h.Comma(int64(len(shareRecords))))
+		if err := verifyShareRecords(balancesByAddr, shareRecords); err != nil {
+			panic(err)
+		}
+
+		unbondingsByAddr, err := parseUnbondingsByAddr(src)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%s unbonding delegators\n", h.Comma(int64(len(unbondingsByAddr))))
+
+		rewardsByAddr, err := parseRewardsByAddr(src, delegsByAddr, shareRecords)
 		if err != nil {
 			panic(err)
 		}
-		fmt.Printf("%s accounts\n", h.Comma(int64(len(accountTypesByAddr))))
+		fmt.Printf("%s delegators with outstanding rewards\n", h.Comma(int64(len(rewardsByAddr))))
+
+		// Vesting schedules are evaluated at the end of the voting period
+		var snapshotTime time.Time
+		if len(vestingAccsByAddr) > 0 {
+			prop, err := parseProp(src)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Cannot evaluate the vesting schedules at the voting end time: %v\n", err)
+				os.Exit(1)
+			}
+			snapshotTime = prop.VotingEndTime
+			fmt.Printf("Vesting schedules evaluated at %s\n", snapshotTime)
+		}
 
-		accounts := getAccounts(delegsByAddr, votesByAddr, valsByAddr, balancesByAddr, accountTypesByAddr)
+		accounts := getAccounts(delegsByAddr, votesByAddr, valsByAddr, balancesByAddr, denomWeights,
+			accountTypesByAddr, shareRecords, unbondingsByAddr, vestingAccsByAddr, snapshotTime, rewardsByAddr)
 
 		bz, err := json.MarshalIndent(accounts, "", "  ")
 		if err != nil {
@@ -118,7 +514,72 @@ func main() {
 			panic(err)
 		}
 		fmt.Printf("%s file created.\n", accountsFile)
+
+	case "explain":
+		printExplanation(newExplanation(address, votesByAddr, valsByAddr, delegsByAddr, balancesByAddr))
+		if _, err := os.Stat(accountsFile); os.IsNotExist(err) {
+			fmt.Printf("WARNING: %s not found, run the accounts command to explain the airdrop\n", accountsFile)
+			break
+		}
+		accounts, err := parseAccounts(accountsFile)
+		if err != nil {
+			panic(err)
+		}
+		params, err := newDistributionParams(unbondingMultiplier, lockedMultiplier)
+		if err != nil {
+			panic(err)
+		}
+		e, err := newAirdropExplanation(accounts, address, params)
+		if err != nil {
+			panic(err)
+		}
+		printAirdropExplanation(e)
+	}
+}
+
+// tallyParamsOrDefault returns the tally params of src, or the default ones
+// if the dataset doesn't have them, with a warning written to w.
+func tallyParamsOrDefault(w io.Writer, src dataSource) govtypes.TallyParams {
+	params, err := parseTallyParams(src)
+	if err != nil {
+		panic(err)
+	}
+	if params == nil {
+		fmt.Fprintln(w, "WARNING: tally_params.json not found, using the default tally params")
+		return govtypes.DefaultTallyParams()
+	}
+	return *params
+}
+
+// newTallyRuleOrExit returns the tally rule named name, with threshold
+// replacing the params threshold if not empty. The rule name is written to w.
+func newTallyRuleOrExit(w io.Writer, name, threshold string, params govtypes.TallyParams) tallyRule {
+	var thresholdDec *sdk.Dec
+	if threshold != "" {
+		d, err := sdk.NewDecFromStr(threshold)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid threshold %q: %v\n", threshold, err)
+			os.Exit(1)
+		}
+		thresholdDec = &d
+	}
+	rule, err := newTallyRule(name, params, thresholdDec)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(w, "Tally rule %s\n", name)
+	return rule
+}
+
+func usage(flags *flag.FlagSet) {
+	fmt.Fprintf(os.Stderr, "Usage:\n%s [%s] [flags] [datapath]\n",
+		filepath.Base(os.Args[0]), strings.Join(commands, "|"))
+	fmt.Fprintf(os.Stderr, "%s explain [flags] [address] [datapath]\n", filepath.Base(os.Args[0]))
+	if flags != nil {
+		flags.PrintDefaults()
 	}
+	os.Exit(1)
 }
 
 func human(i sdk.Int) string {
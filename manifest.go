@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+)
+
+const manifestFile = "manifest.json"
+
+// manifest describes a published dataset, so reviewers can verify that a
+// result was computed from the same files.
+type manifest struct {
+	ProposalID uint64         `json:"proposal_id"`
+	Height     int64          `json:"height"`
+	Files      []manifestItem `json:"files"`
+}
+
+type manifestItem struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	MD5    string `json:"md5"`
+}
+
+// datasetFiles returns the files that can be part of a dataset directory.
+// accounts.json is computed from the other files, but it's the input of the
+// genesis and distribution commands.
+func datasetFiles() []string {
+	files := []string{"active_validators.json", "tally_votes.json", "accounts.json"}
+	for file := range exportPaths {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// writeManifest computes the checksums of the dataset files present in dir
+// and writes them in the manifest file.
+func writeManifest(dir string, propID uint64, height int64) (string, error) {
+	m := manifest{
+		ProposalID: propID,
+		Height:     height,
+	}
+	for _, file := range datasetFiles() {
+		path, err := findFile(filepath.Join(dir, file))
+		if err != nil {
+			// Not part of the dataset
+			continue
+		}
+		item, err := checksumFile(path)
+		if err != nil {
+			return "", err
+		}
+		item.Name = filepath.Base(path)
+		m.Files = append(m.Files, item)
+	}
+	if len(m.Files) == 0 {
+		return "", fmt.Errorf("no dataset files found in %s", dir)
+	}
+	bz, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, manifestFile)
+	return path, os.WriteFile(path, bz, 0o666)
+}
+
+// verifyManifest checks the dataset files of dir against the manifest file,
+// if any, except the skipped ones. It returns false if there's no manifest.
+func verifyManifest(dir string, propID uint64, skipped ...string) (bool, error) {
+	bz, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var m manifest
+	if err := json.Unmarshal(bz, &m); err != nil {
+		return false, fmt.Errorf("cannot json decode %s: %w", manifestFile, err)
+	}
+	if propID != 0 && m.ProposalID != 0 && propID != m.ProposalID {
+		return false, fmt.Errorf("manifest is for proposal %d, not %d", m.ProposalID, propID)
+	}
+	for _, f := range m.Files {
+		if !filepath.IsLocal(f.Name) {
+			// Don't hash files outside of the dataset
+			return false, fmt.Errorf("%s: invalid file name, it must be relative to the dataset directory", f.Name)
+		}
+		if slices.Contains(skipped, f.Name) {
+			continue
+		}
+		item, err := checksumFile(filepath.Join(dir, f.Name))
+		if err != nil {
+			return false, err
+		}
+		if f.SHA256 != "" && item.SHA256 != f.SHA256 {
+			return false, fmt.Errorf("%s: sha256 mismatch, expected %s got %s", f.Name, f.SHA256, item.SHA256)
+		}
+		if f.MD5 != "" && item.MD5 != f.MD5 {
+			return false, fmt.Errorf("%s: md5 mismatch, expected %s got %s", f.Name, f.MD5, item.MD5)
+		}
+		if f.SHA256 == "" && f.MD5 == "" {
+			return false, fmt.Errorf("%s: no checksum", f.Name)
+		}
+	}
+	return true, nil
+}
+
+// checksumFile returns the sha256 and md5 of the file, as stored on disk
+// (compressed files are not decompressed).
+func checksumFile(path string) (manifestItem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return manifestItem{}, err
+	}
+	defer f.Close()
+	var (
+		sha256Hash = sha256.New()
+		md5Hash    = md5.New()
+	)
+	if _, err := io.Copy(io.MultiWriter(sha256Hash, md5Hash), f); err != nil {
+		return manifestItem{}, err
+	}
+	return manifestItem{
+		SHA256: hex.EncodeToString(sha256Hash.Sum(nil)),
+		MD5:    hex.EncodeToString(md5Hash.Sum(nil)),
+	}, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifest(t *testing.T) {
+	tests := []struct {
+		name        string
+		propID      uint64
+		tamper      func(dir string)
+		expectedErr string
+	}{
+		{
+			name:   "ok",
+			propID: 848,
+		},
+		{
+			name: "ok without proposal id",
+		},
+		{
+			name:        "wrong proposal id",
+			propID:      847,
+			expectedErr: "manifest is for proposal 848, not 847",
+		},
+		{
+			name:   "modified file",
+			propID: 848,
+			tamper: func(dir string) {
+				f, err := os.OpenFile(filepath.Join(dir, "prop.json"), os.O_APPEND|os.O_WRONLY, 0)
+				require.NoError(t, err)
+				defer f.Close()
+				_, err = f.WriteString(" ")
+				require.NoError(t, err)
+			},
+			expectedErr: "prop.json: sha256 mismatch",
+		},
+		{
+			name:   "absolute file name",
+			propID: 848,
+			tamper: func(dir string) {
+				writeTestManifest(t, dir, "/etc/hostname")
+			},
+			expectedErr: "/etc/hostname: invalid file name",
+		},
+		{
+			name:   "file name outside of the dataset",
+			propID: 848,
+			tamper: func(dir string) {
+				writeTestManifest(t, dir, "../votes.json")
+			},
+			expectedErr: "../votes.json: invalid file name",
+		},
+		{
+			name:   "modified accounts",
+			propID: 848,
+			tamper: func(dir string) {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "accounts.json"), []byte("[{}]"), 0o666))
+			},
+			expectedErr: "accounts.json: sha256 mismatch",
+		},
+		{
+			name:   "missing file",
+			propID: 848,
+			tamper: func(dir string) {
+				require.NoError(t, os.Remove(filepath.Join(dir, "votes.json")))
+			},
+			expectedErr: "no such file or directory",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			src := exportSource
+			src.dir = t.TempDir()
+			_, _, err := extract(src)
+			require.NoError(err)
+			require.NoError(os.WriteFile(filepath.Join(src.dir, "accounts.json"), []byte("[]"), 0o666))
+			_, err = writeManifest(src.dir, 848, 18010658)
+			require.NoError(err)
+			if tt.tamper != nil {
+				tt.tamper(src.dir)
+			}
+
+			verified, err := verifyManifest(src.dir, tt.propID)
+
+			if tt.expectedErr != "" {
+				require.ErrorContains(err, tt.expectedErr)
+				return
+			}
+			require.NoError(err)
+			assert.True(t, verified)
+		})
+	}
+}
+
+func TestVerifyManifestSkipped(t *testing.T) {
+	require := require.New(t)
+	src := exportSource
+	src.dir = t.TempDir()
+	_, _, err := extract(src)
+	require.NoError(err)
+	require.NoError(os.WriteFile(filepath.Join(src.dir, "accounts.json"), []byte("[]"), 0o666))
+	_, err = writeManifest(src.dir, 848, 18010658)
+	require.NoError(err)
+	require.NoError(os.WriteFile(filepath.Join(src.dir, "accounts.json"), []byte("[{}]"), 0o666))
+
+	verified, err := verifyManifest(src.dir, 848, "accounts.json")
+
+	require.NoError(err)
+	assert.True(t, verified)
+}
+
+func TestVerifyManifestMissing(t *testing.T) {
+	verified, err := verifyManifest(t.TempDir(), 848)
+
+	require.NoError(t, err)
+	assert.False(t, verified)
+}
+
+// writeTestManifest overwrites the manifest of dir with a single file.
+func writeTestManifest(t *testing.T, dir, name string) {
+	bz, err := json.Marshal(manifest{
+		ProposalID: 848,
+		Files:      []manifestItem{{Name: name, SHA256: "00"}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, manifestFile), bz, 0o666))
+}
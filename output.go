@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	h "github.com/dustin/go-humanize"
+	"github.com/olekukonko/tablewriter"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+)
+
+// tallyFormats are the output formats of the tally results.
+var tallyFormats = []string{"table", "json", "csv", "markdown"}
+
+// tallyRow is a row of the tally results, amounts are in the base denom.
+type tallyRow struct {
+	Source     string  `json:"source"`
+	Yes        sdk.Int `json:"yes"`
+	No         sdk.Int `json:"no"`
+	NoWithVeto sdk.Int `json:"no_with_veto"`
+	Abstain    sdk.Int `json:"abstain"`
+	Total      sdk.Int `json:"total"`
+}
+
+// newTallyRows returns the computed, from prop and diff rows of the tally
+// results.
+func newTallyRows(res tallyResult, prop govtypes.Proposal) []tallyRow {
+	newRow := func(source string, t govtypes.TallyResult) tallyRow {
+		return tallyRow{
+			Source:     source,
+			Yes:        t.Yes,
+			No:         t.No,
+			NoWithVeto: t.NoWithVeto,
+			Abstain:    t.Abstain,
+			Total:      t.Yes.Add(t.No).Add(t.NoWithVeto).Add(t.Abstain),
+		}
+	}
+	computed := govtypes.NewTallyResultFromMap(res.Results)
+	diff := govtypes.NewTallyResult(
+		computed.Yes.Sub(prop.FinalTallyResult.Yes),
+		computed.Abstain.Sub(prop.FinalTallyResult.Abstain),
+		computed.No.Sub(prop.FinalTallyResult.No),
+		computed.NoWithVeto.Sub(prop.FinalTallyResult.NoWithVeto),
+	)
+	return []tallyRow{
+		newRow("computed", computed),
+		newRow("from prop", prop.FinalTallyResult),
+		newRow("diff", diff),
+	}
+}
+
+// writeTallyRows writes the tally rows to w in format, one of tallyFormats
+// except table. Amounts are in the base denom, with thousands separators in
+// Markdown.
+func writeTallyRows(w io.Writer, rows []tallyRow, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rows); err != nil {
+			return fmt.Errorf("cannot json encode tally: %w", err)
+		}
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"source", "yes", "no", "no_with_veto", "abstain", "total"})
+		for _, r := range rows {
+			cw.Write([]string{
+				r.Source, r.Yes.String(), r.No.String(), r.NoWithVeto.String(), r.Abstain.String(), r.Total.String(),
+			})
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return fmt.Errorf("cannot write tally csv: %w", err)
+		}
+	case "markdown":
+		table := tablewriter.NewWriter(w)
+		table.SetHeader([]string{"", "Yes", "No", "NoWithVeto", "Abstain", "Total"})
+		table.SetAutoFormatHeaders(false)
+		table.SetBorders(tablewriter.Border{Left: true, Right: true})
+		table.SetCenterSeparator("|")
+		for _, r := range rows {
+			table.Append([]string{
+				r.Source, comma(r.Yes), comma(r.No), comma(r.NoWithVeto), comma(r.Abstain), comma(r.Total),
+			})
+		}
+		table.Render()
+	default:
+		return fmt.Errorf("unknown tally format %q", format)
+	}
+	return nil
+}
+
+// comma returns i in the base denom with thousands separators.
+func comma(i sdk.Int) string {
+	return h.BigComma(i.BigInt())
+}
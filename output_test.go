@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+)
+
+func TestWriteTallyRows(t *testing.T) {
+	res := tallyResult{
+		Results: map[govtypes.VoteOption]sdk.Dec{
+			govtypes.OptionYes:        sdk.NewDec(4_000_000),
+			govtypes.OptionNo:         sdk.NewDec(2_000_000),
+			govtypes.OptionNoWithVeto: sdk.ZeroDec(),
+			govtypes.OptionAbstain:    sdk.NewDec(1_000_000),
+		},
+	}
+	prop := govtypes.Proposal{
+		FinalTallyResult: govtypes.NewTallyResult(sdk.NewInt(3_999_500), sdk.NewInt(1_000_000), sdk.NewInt(2_000_000), sdk.ZeroInt()),
+	}
+	rows := newTallyRows(res, prop)
+	tests := []struct {
+		format         string
+		expectedOutput string
+		expectedErr    string
+	}{
+		{
+			format: "json",
+			expectedOutput: `[
+  {
+    "source": "computed",
+    "yes": "4000000",
+    "no": "2000000",
+    "no_with_veto": "0",
+    "abstain": "1000000",
+    "total": "7000000"
+  },
+  {
+    "source": "from prop",
+    "yes": "3999500",
+    "no": "2000000",
+    "no_with_veto": "0",
+    "abstain": "1000000",
+    "total": "6999500"
+  },
+  {
+    "source": "diff",
+    "yes": "500",
+    "no": "0",
+    "no_with_veto": "0",
+    "abstain": "0",
+    "total": "500"
+  }
+]
+`,
+		},
+		{
+			format: "csv",
+			expectedOutput: `source,yes,no,no_with_veto,abstain,total
+computed,4000000,2000000,0,1000000,7000000
+from prop,3999500,2000000,0,1000000,6999500
+diff,500,0,0,0,500
+`,
+		},
+		{
+			format: "markdown",
+			// amounts are in the base denom, the diff isn't truncated
+			expectedOutput: `|           |    Yes    |    No     | NoWithVeto |  Abstain  |   Total   |
+|-----------|-----------|-----------|------------|-----------|-----------|
+| computed  | 4,000,000 | 2,000,000 |          0 | 1,000,000 | 7,000,000 |
+| from prop | 3,999,500 | 2,000,000 |          0 | 1,000,000 | 6,999,500 |
+| diff      |       500 |         0 |          0 |         0 |       500 |
+`,
+		},
+		{
+			format:      "xml",
+			expectedErr: `unknown tally format "xml"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			err := writeTallyRows(&buf, rows, tt.format)
+
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedOutput, buf.String())
+		})
+	}
+}
@@ -1,10 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
+	"sort"
 
 	"github.com/gogo/protobuf/jsonpb"
 
@@ -12,12 +13,15 @@ import (
 	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	vestexported "github.com/cosmos/cosmos-sdk/x/auth/vesting/exported"
 	vestingtypes "github.com/cosmos/cosmos-sdk/x/auth/vesting/types"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
 	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
 	proposaltypes "github.com/cosmos/cosmos-sdk/x/params/types/proposal"
 	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
 	icatypes "github.com/cosmos/ibc-go/v4/modules/apps/27-interchain-accounts/types"
+	transfertypes "github.com/cosmos/ibc-go/v4/modules/apps/transfer/types"
 )
 
 var (
@@ -37,7 +41,7 @@ func init() {
 }
 
 func parseAccounts(path string) ([]Account, error) {
-	f, err := os.Open(path)
+	f, err := openFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("cannot read %s file, run `%s accounts` to generate it: %w", path, os.Args[0], err)
 	}
@@ -49,138 +53,542 @@ func parseAccounts(path string) ([]Account, error) {
 	return accounts, nil
 }
 
-func parseAccountTypesPerAddr(path string) (map[string]string, error) {
-	f, err := os.Open(filepath.Join(path, "auth_genesis.json"))
+// parseAuthAccounts returns the type of the accounts, and the vesting
+// accounts.
+func parseAuthAccounts(src dataSource) (map[string]string, map[string]vestexported.VestingAccount, error) {
+	dec, f, err := src.open("auth_genesis.json")
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer f.Close()
 	var genesis authtypes.GenesisState
-	err = unmarshaler.Unmarshal(f, &genesis)
+	err = unmarshaler.UnmarshalNext(dec, &genesis)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	accountTypesPerAddr := make(map[string]string)
+	var (
+		accountTypesPerAddr = make(map[string]string)
+		vestingAccsByAddr   = make(map[string]vestexported.VestingAccount)
+	)
 	for i, any := range genesis.Accounts {
 		var acc authtypes.GenesisAccount
 		registry.UnpackAny(any, &acc)
-		accountTypesPerAddr[acc.GetAddress().String()] = genesis.Accounts[i].GetTypeUrl()
+		addr := acc.GetAddress().String()
+		accountTypesPerAddr[addr] = genesis.Accounts[i].GetTypeUrl()
+		if vacc, ok := acc.(vestexported.VestingAccount); ok {
+			vestingAccsByAddr[addr] = vacc
+		}
 	}
-	return accountTypesPerAddr, nil
+	return accountTypesPerAddr, vestingAccsByAddr, nil
 }
 
-func parseVotesByAddr(path string) (map[string]govtypes.WeightedVoteOptions, error) {
-	f, err := os.Open(filepath.Join(path, "votes.json"))
+// parseVotesByAddr returns the merged votes of the vote sources of src, along
+// with the votes that have been overridden by a subsequent vote.
+func parseVotesByAddr(src dataSource) (map[string]govtypes.WeightedVoteOptions, []voteOverride, error) {
+	votes, overrides, err := mergeVotes(src.voteSources(), src.propID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer f.Close()
-	// XXX workaround to unmarshal votes because proto doesn't support top-level array
-	dec := json.NewDecoder(f)
-	_, err = dec.Token()
+	votesByAddr := make(map[string]govtypes.WeightedVoteOptions, len(votes))
+	for _, vote := range votes {
+		votesByAddr[vote.Voter] = vote.Options
+	}
+	return votesByAddr, overrides, nil
+}
+
+// parseVotesByProp returns the merged votes of the vote sources of src for
+// each proposal of propIDs, along with the votes that have been overridden by
+// a subsequent vote.
+func parseVotesByProp(src dataSource, propIDs []uint64) (map[uint64]map[string]govtypes.WeightedVoteOptions, []voteOverride, error) {
+	votes, overrides, err := mergeVotes(src.voteSources(), 0)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	votesByAddr := make(map[string]govtypes.WeightedVoteOptions)
-	for dec.More() {
-		var vote govtypes.Vote
-		err := unmarshaler.UnmarshalNext(dec, &vote)
-		if err != nil {
-			return nil, err
+	votesByProp := make(map[uint64]map[string]govtypes.WeightedVoteOptions, len(propIDs))
+	for _, propID := range propIDs {
+		votesByProp[propID] = make(map[string]govtypes.WeightedVoteOptions)
+	}
+	for _, vote := range votes {
+		if votesByAddr, ok := votesByProp[vote.ProposalId]; ok {
+			votesByAddr[vote.Voter] = vote.Options
+		}
+	}
+	var propOverrides []voteOverride
+	for _, o := range overrides {
+		if _, ok := votesByProp[o.Vote.ProposalId]; ok {
+			propOverrides = append(propOverrides, o)
 		}
-		votesByAddr[vote.Voter] = vote.Options
 	}
-	return votesByAddr, nil
+	return votesByProp, propOverrides, nil
 }
 
-func parseDelegationsByAddr(path string) (map[string][]stakingtypes.Delegation, error) {
-	f, err := os.Open(filepath.Join(path, "delegations.json"))
+func parseDelegationsByAddr(src dataSource) (map[string][]stakingtypes.Delegation, error) {
+	dec, f, err := src.open("delegations.json")
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
-	var delegs []stakingtypes.Delegation
-	err = json.NewDecoder(f).Decode(&delegs)
+	// Decode delegations one by one to avoid loading the whole array in memory
+	_, err = dec.Token()
 	if err != nil {
 		return nil, err
 	}
 	delegsByAddr := make(map[string][]stakingtypes.Delegation)
-	for _, d := range delegs {
+	for dec.More() {
+		var d stakingtypes.Delegation
+		err := dec.Decode(&d)
+		if err != nil {
+			return nil, err
+		}
 		delegsByAddr[d.DelegatorAddress] = append(delegsByAddr[d.DelegatorAddress], d)
 	}
 	return delegsByAddr, nil
 }
 
-func parseValidatorsByAddr(path string, votesByAddr map[string]govtypes.WeightedVoteOptions) (map[string]govtypes.ValidatorGovInfo, error) {
-	f, err := os.Open(filepath.Join(path, "active_validators.json"))
+func parseValidatorsByAddr(src dataSource, votesByAddr map[string]govtypes.WeightedVoteOptions) (map[string]govtypes.ValidatorGovInfo, error) {
+	vals, err := parseActiveValidators(src)
+	if err != nil {
+		return nil, err
+	}
+	return newValidatorsByAddr(vals, votesByAddr), nil
+}
+
+// newValidatorsByAddr returns the gov info of the validators, with the votes
+// of their account.
+func newValidatorsByAddr(
+	vals []stakingtypes.Validator, votesByAddr map[string]govtypes.WeightedVoteOptions,
+) map[string]govtypes.ValidatorGovInfo {
+	valsByAddr := make(map[string]govtypes.ValidatorGovInfo)
+	for _, val := range vals {
+		valAddr, err := sdk.ValAddressFromBech32(val.OperatorAddress)
+		if err != nil {
+			panic(err)
+		}
+		accAddr := sdk.AccAddress(valAddr.Bytes()).String()
+		valsByAddr[val.OperatorAddress] = govtypes.NewValidatorGovInfo(
+			val.GetOperator(),
+			val.GetBondedTokens(),
+			val.GetDelegatorShares(),
+			sdk.ZeroDec(),
+			votesByAddr[accAddr],
+		)
+	}
+	return valsByAddr
+}
+
+func parseValidators(src dataSource, file string) ([]stakingtypes.Validator, error) {
+	dec, f, err := src.open(file)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
 	// XXX workaround to unmarshal validators because proto doesn't support top-level array
-	dec := json.NewDecoder(f)
 	_, err = dec.Token()
 	if err != nil {
 		return nil, err
 	}
-	valsByAddr := make(map[string]govtypes.ValidatorGovInfo)
+	var vals []stakingtypes.Validator
 	for dec.More() {
 		var val stakingtypes.Validator
 		err := unmarshaler.UnmarshalNext(dec, &val)
 		if err != nil {
 			return nil, err
 		}
+		vals = append(vals, val)
+	}
+	return vals, nil
+}
 
-		valAddr, err := sdk.ValAddressFromBech32(val.OperatorAddress)
+func parseStakingParams(src dataSource) (stakingtypes.Params, error) {
+	dec, f, err := src.open("staking_params.json")
+	if err != nil {
+		return stakingtypes.Params{}, err
+	}
+	defer f.Close()
+	var params stakingtypes.Params
+	err = unmarshaler.UnmarshalNext(dec, &params)
+	return params, err
+}
+
+// parseTallyParams returns the gov tally params. Datasets without the
+// tally_params.json file return nil.
+func parseTallyParams(src dataSource) (*govtypes.TallyParams, error) {
+	dec, f, err := src.openOptional("tally_params.json")
+	if err != nil || dec == nil {
+		return nil, err
+	}
+	defer f.Close()
+	var params govtypes.TallyParams
+	err = unmarshaler.UnmarshalNext(dec, &params)
+	return &params, err
+}
+
+// parseTotalBondedTokens returns the balance of the bonded pool, which is the
+// total bonded tokens used by the gov module to compute the quorum. Datasets
+// without the balances.json or staking_params.json files return the sum of
+// the tokens of the bonded validators, which differs from the pool balance by
+// the tokens sent directly to the pool.
+func parseTotalBondedTokens(src dataSource) (sdk.Int, error) {
+	if !src.has("balances.json") || !src.has("staking_params.json") {
+		return parseBondedValidatorsTokens(src)
+	}
+	params, err := parseStakingParams(src)
+	if err != nil {
+		return sdk.Int{}, err
+	}
+	dec, f, err := src.open("balances.json")
+	if err != nil {
+		return sdk.Int{}, err
+	}
+	defer f.Close()
+	// Decode balances one by one to avoid loading the whole array in memory
+	_, err = dec.Token()
+	if err != nil {
+		return sdk.Int{}, err
+	}
+	poolAddr := authtypes.NewModuleAddress(stakingtypes.BondedPoolName).String()
+	for dec.More() {
+		var b banktypes.Balance
+		err := dec.Decode(&b)
 		if err != nil {
-			panic(err)
+			return sdk.Int{}, err
 		}
-		accAddr := sdk.AccAddress(valAddr.Bytes()).String()
-		valsByAddr[val.OperatorAddress] = govtypes.NewValidatorGovInfo(
-			val.GetOperator(),
-			val.GetBondedTokens(),
-			val.GetDelegatorShares(),
-			sdk.ZeroDec(),
-			votesByAddr[accAddr],
-		)
+		if b.Address == poolAddr {
+			return b.Coins.AmountOf(params.BondDenom), nil
+		}
+	}
+	return sdk.ZeroInt(), nil
+}
+
+// parseBondedValidatorsTokens returns the sum of the tokens of the bonded
+// validators.
+func parseBondedValidatorsTokens(src dataSource) (sdk.Int, error) {
+	file := "validators.json"
+	if !src.has(file) {
+		file = "active_validators.json"
+	}
+	vals, err := parseValidators(src, file)
+	if err != nil {
+		return sdk.Int{}, err
+	}
+	total := sdk.ZeroInt()
+	for _, val := range vals {
+		if val.IsBonded() {
+			total = total.Add(val.Tokens)
+		}
+	}
+	return total, nil
+}
+
+// parseBondDenomSupply returns the total supply of the staking bond denom.
+// Datasets without the supply.json or staking_params.json files return nil.
+func parseBondDenomSupply(src dataSource) (*sdk.Int, error) {
+	if !src.has("supply.json") || !src.has("staking_params.json") {
+		return nil, nil
+	}
+	params, err := parseStakingParams(src)
+	if err != nil {
+		return nil, err
+	}
+	supply, err := parseSupply(src)
+	if err != nil {
+		return nil, err
 	}
-	return valsByAddr, nil
+	amount := supply.AmountOf(params.BondDenom)
+	return &amount, nil
 }
 
-func parseProp(path string) govtypes.Proposal {
-	f, err := os.Open(filepath.Join(path, "prop.json"))
+// parseSupply returns the total supply of the bank module.
+func parseSupply(src dataSource) (sdk.Coins, error) {
+	dec, f, err := src.open("supply.json")
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	defer f.Close()
+	var supply sdk.Coins
+	if err := dec.Decode(&supply); err != nil {
+		return nil, fmt.Errorf("cannot decode supply.json: %w", err)
+	}
+	return supply, nil
+}
+
+// parseActiveValidators returns the active validator set, computed from all
+// the validators and the staking params. For datasets that don't have these
+// files, the active_validators.json file is read instead, which must contain
+// the already filtered active set.
+func parseActiveValidators(src dataSource) ([]stakingtypes.Validator, error) {
+	if !src.has("validators.json") || !src.has("staking_params.json") {
+		return parseValidators(src, "active_validators.json")
+	}
+	vals, err := parseValidators(src, "validators.json")
+	if err != nil {
+		return nil, err
+	}
+	params, err := parseStakingParams(src)
+	if err != nil {
+		return nil, err
+	}
+	return activeValidators(vals, params.MaxValidators), nil
+}
+
+// activeValidators returns the validators that take part in the tally, with
+// the same order as staking.Keeper.IterateBondedValidatorsByPower: bonded
+// validators sorted by consensus power (tokens are truncated by the power
+// reduction) and then by operator address, limited to maxValidators.
+//
+// Jailed validators are excluded because they are removed from the power
+// index.
+func activeValidators(vals []stakingtypes.Validator, maxValidators uint32) []stakingtypes.Validator {
+	var bonded []stakingtypes.Validator
+	for _, val := range vals {
+		if !val.IsBonded() || val.IsJailed() {
+			continue
+		}
+		bonded = append(bonded, val)
+	}
+	return topValidatorsByPower(bonded, maxValidators)
+}
+
+// topValidatorsByPower returns the maxValidators first validators, sorted
+// like the power index.
+func topValidatorsByPower(vals []stakingtypes.Validator, maxValidators uint32) []stakingtypes.Validator {
+	type valPower struct {
+		val   stakingtypes.Validator
+		power int64
+		addr  sdk.ValAddress
+	}
+	sorted := make([]valPower, len(vals))
+	for i, val := range vals {
+		sorted[i] = valPower{
+			val:   val,
+			power: val.PotentialConsensusPower(sdk.DefaultPowerReduction),
+			addr:  val.GetOperator(),
+		}
+	}
+	// The power index key is power|addrLen|^addr, iterated in reverse order,
+	// hence the descending power and then the ascending address.
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].power != sorted[j].power {
+			return sorted[i].power > sorted[j].power
+		}
+		if len(sorted[i].addr) != len(sorted[j].addr) {
+			return len(sorted[i].addr) > len(sorted[j].addr)
+		}
+		return bytes.Compare(sorted[i].addr, sorted[j].addr) < 0
+	})
+	if len(sorted) > int(maxValidators) {
+		sorted = sorted[:maxValidators]
+	}
+	top := make([]stakingtypes.Validator, len(sorted))
+	for i, v := range sorted {
+		top[i] = v.val
+	}
+	return top
+}
+
+// parseProp returns the proposal of the dataset, or the proposal src.propID
+// of the tally export.
+func parseProp(src dataSource) (govtypes.Proposal, error) {
+	if src.fromExport() {
+		props, err := parseProps(src, []uint64{src.propID})
+		if err != nil {
+			return govtypes.Proposal{}, err
+		}
+		return props[src.propID], nil
+	}
+	dec, f, err := src.open("prop.json")
+	if err != nil {
+		return govtypes.Proposal{}, err
 	}
 	defer f.Close()
 	var prop govtypes.Proposal
-	err = unmarshaler.Unmarshal(f, &prop)
+	if err := unmarshaler.UnmarshalNext(dec, &prop); err != nil {
+		return govtypes.Proposal{}, fmt.Errorf("prop.json: %w", err)
+	}
+	return prop, nil
+}
+
+// parseProps returns the proposals of propIDs read from the exports.
+func parseProps(src dataSource, propIDs []uint64) (map[uint64]govtypes.Proposal, error) {
+	dec, f, err := src.open("prop.json")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	props := make(map[uint64]govtypes.Proposal, len(propIDs))
+	for _, propID := range propIDs {
+		props[propID] = govtypes.Proposal{}
+	}
+	// Exports contain all the proposals, only the ones of propIDs are
+	// unmarshaled with the registry.
+	err = forEachRaw(dec, func(raw json.RawMessage) error {
+		var id struct {
+			ProposalID uint64 `json:"proposal_id,string"`
+		}
+		if err := json.Unmarshal(raw, &id); err != nil {
+			return err
+		}
+		if _, ok := props[id.ProposalID]; !ok {
+			return nil
+		}
+		var prop govtypes.Proposal
+		if err := unmarshaler.Unmarshal(bytes.NewReader(raw), &prop); err != nil {
+			// The content is not used, so read proposals of types not
+			// registered here without it.
+			var fields map[string]json.RawMessage
+			if json.Unmarshal(raw, &fields) != nil {
+				return err
+			}
+			delete(fields, "content")
+			raw, _ = json.Marshal(fields)
+			if err := unmarshaler.Unmarshal(bytes.NewReader(raw), &prop); err != nil {
+				return err
+			}
+		}
+		if prop.FinalTallyResult.Yes.IsNil() {
+			// Proposals still in voting period have no final tally
+			prop.FinalTallyResult = govtypes.EmptyTallyResult()
+		}
+		props[id.ProposalID] = prop
+		return nil
+	})
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	for _, propID := range propIDs {
+		if props[propID].ProposalId == 0 {
+			return nil, fmt.Errorf("proposal %d not found in %s", propID, src.tallyExport)
+		}
 	}
-	return prop
+	return props, nil
 }
 
-func parseBalancesByAddr(path, denom string) (map[string]sdk.Coin, error) {
-	f, err := os.Open(filepath.Join(path, "balances.json"))
+// parseBalancesByAddr returns the balances, filtered by the denoms of
+// denomWeights and the LSM tokenized shares denoms.
+func parseBalancesByAddr(src dataSource, denomWeights map[string]sdk.Dec) (map[string]sdk.Coins, error) {
+	dec, f, err := src.open("balances.json")
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
 	var balances []banktypes.Balance
-	err = json.NewDecoder(f).Decode(&balances)
+	err = dec.Decode(&balances)
 	if err != nil {
 		return nil, err
 	}
-	balancesByAddr := make(map[string]sdk.Coin)
+	balancesByAddr := make(map[string]sdk.Coins)
 	for _, b := range balances {
+		var coins sdk.Coins
 		for _, c := range b.Coins {
-			// Filter denom
-			if c.Denom == denom {
-				balancesByAddr[b.Address] = c
-				break
+			// Filter denoms, tokenized shares are always kept
+			if _, ok := denomWeights[c.Denom]; ok {
+				coins = append(coins, c)
+			} else if _, ok := shareDenomValidator(c.Denom); ok {
+				coins = append(coins, c)
 			}
 		}
+		if len(coins) > 0 {
+			balancesByAddr[b.Address] = coins
+		}
 	}
 	return balancesByAddr, nil
 }
+
+// parseDenomTraces returns the IBC denom traces. Datasets without the
+// denom_traces.json file return no traces.
+func parseDenomTraces(src dataSource) (transfertypes.Traces, error) {
+	dec, f, err := src.openOptional("denom_traces.json")
+	if err != nil || dec == nil {
+		return nil, err
+	}
+	defer f.Close()
+	var traces transfertypes.Traces
+	err = dec.Decode(&traces)
+	return traces, err
+}
+
+// parseTokenizeShareRecords returns the LSM tokenize share records. Datasets
+// without the tokenize_share_records.json file return no records.
+func parseTokenizeShareRecords(src dataSource) ([]stakingtypes.TokenizeShareRecord, error) {
+	dec, f, err := src.openOptional("tokenize_share_records.json")
+	if err != nil || dec == nil {
+		return nil, err
+	}
+	defer f.Close()
+	// XXX workaround to unmarshal records because proto doesn't support top-level array
+	_, err = dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	var records []stakingtypes.TokenizeShareRecord
+	for dec.More() {
+		var record stakingtypes.TokenizeShareRecord
+		err := unmarshaler.UnmarshalNext(dec, &record)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// parseUnbondingsByAddr returns the amount of tokens being unbonded per
+// delegator, summed over all validators and entries. Datasets without the
+// unbonding_delegations.json file return no unbondings.
+func parseUnbondingsByAddr(src dataSource) (map[string]sdk.Int, error) {
+	dec, f, err := src.openOptional("unbonding_delegations.json")
+	if err != nil || dec == nil {
+		return nil, err
+	}
+	defer f.Close()
+	// XXX workaround to unmarshal unbondings because proto doesn't support top-level array
+	_, err = dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	unbondingsByAddr := make(map[string]sdk.Int)
+	for dec.More() {
+		var ubd stakingtypes.UnbondingDelegation
+		err := unmarshaler.UnmarshalNext(dec, &ubd)
+		if err != nil {
+			return nil, err
+		}
+		amount, ok := unbondingsByAddr[ubd.DelegatorAddress]
+		if !ok {
+			amount = sdk.ZeroInt()
+		}
+		for _, e := range ubd.Entries {
+			// Balance is the initial balance minus the slashes occurred during
+			// the unbonding.
+			amount = amount.Add(e.Balance)
+		}
+		unbondingsByAddr[ubd.DelegatorAddress] = amount
+	}
+	return unbondingsByAddr, nil
+}
+
+// parseRewardsByAddr returns the outstanding staking rewards per delegator,
+// computed from the distribution module genesis. Datasets without the
+// distribution_genesis.json file return no rewards.
+func parseRewardsByAddr(
+	src dataSource,
+	delegsByAddr map[string][]stakingtypes.Delegation,
+	shareRecords []stakingtypes.TokenizeShareRecord,
+) (map[string]sdk.Coins, error) {
+	dec, f, err := src.openOptional("distribution_genesis.json")
+	if err != nil || dec == nil {
+		return nil, err
+	}
+	defer f.Close()
+	var genesis distrtypes.GenesisState
+	err = unmarshaler.UnmarshalNext(dec, &genesis)
+	if err != nil {
+		return nil, err
+	}
+	// Rewards are also computed for the delegations to inactive validators
+	vals, err := parseValidators(src, "validators.json")
+	if err != nil {
+		return nil, err
+	}
+	return getRewardsByAddr(newRewardsCalculator(genesis), vals, delegsByAddr, shareRecords)
+}
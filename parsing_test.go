@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+func TestActiveValidators(t *testing.T) {
+	var (
+		valAddrs = createValidatorAddrs(4)
+		newVal   = func(addr sdk.ValAddress, status stakingtypes.BondStatus, jailed bool, tokens int64) stakingtypes.Validator {
+			return stakingtypes.Validator{
+				OperatorAddress: addr.String(),
+				Status:          status,
+				Jailed:          jailed,
+				Tokens:          sdk.NewInt(tokens),
+				DelegatorShares: sdk.NewDec(tokens),
+			}
+		}
+		bonded   = stakingtypes.Bonded
+		unbonded = stakingtypes.Unbonded
+	)
+	// sort addresses to know which one wins the ties
+	sort.Slice(valAddrs, func(i, j int) bool {
+		return bytes.Compare(valAddrs[i], valAddrs[j]) < 0
+	})
+	tests := []struct {
+		name          string
+		vals          []stakingtypes.Validator
+		maxValidators uint32
+		expectedAddrs []sdk.ValAddress
+	}{
+		{
+			name: "sorted by tokens",
+			vals: []stakingtypes.Validator{
+				newVal(valAddrs[0], bonded, false, 1_000_000),
+				newVal(valAddrs[1], bonded, false, 3_000_000),
+				newVal(valAddrs[2], bonded, false, 2_000_000),
+			},
+			maxValidators: 3,
+			expectedAddrs: []sdk.ValAddress{valAddrs[1], valAddrs[2], valAddrs[0]},
+		},
+		{
+			name: "limited to max validators",
+			vals: []stakingtypes.Validator{
+				newVal(valAddrs[0], bonded, false, 1_000_000),
+				newVal(valAddrs[1], bonded, false, 3_000_000),
+				newVal(valAddrs[2], bonded, false, 2_000_000),
+			},
+			maxValidators: 2,
+			expectedAddrs: []sdk.ValAddress{valAddrs[1], valAddrs[2]},
+		},
+		{
+			name: "unbonded and jailed validators are excluded",
+			vals: []stakingtypes.Validator{
+				newVal(valAddrs[0], bonded, false, 1_000_000),
+				newVal(valAddrs[1], unbonded, false, 3_000_000),
+				newVal(valAddrs[2], bonded, true, 2_000_000),
+			},
+			maxValidators: 2,
+			expectedAddrs: []sdk.ValAddress{valAddrs[0]},
+		},
+		{
+			name: "same consensus power: sorted by address, not by tokens",
+			vals: []stakingtypes.Validator{
+				newVal(valAddrs[3], bonded, false, 2_999_999),
+				newVal(valAddrs[2], bonded, false, 2_500_000),
+				newVal(valAddrs[1], bonded, false, 2_000_000),
+				newVal(valAddrs[0], bonded, false, 1_999_999),
+			},
+			maxValidators: 2,
+			expectedAddrs: []sdk.ValAddress{valAddrs[1], valAddrs[2]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vals := activeValidators(tt.vals, tt.maxValidators)
+
+			var addrs []sdk.ValAddress
+			for _, val := range vals {
+				addrs = append(addrs, val.GetOperator())
+			}
+			assert.Equal(t, tt.expectedAddrs, addrs)
+		})
+	}
+}
+
+func TestParseDelegationsByAddr(t *testing.T) {
+	var (
+		require  = require.New(t)
+		assert   = assert.New(t)
+		accAddrs = createAccountAddrs(3)
+		valAddrs = createValidatorAddrs(2)
+		delegs   = []stakingtypes.Delegation{
+			stakingtypes.NewDelegation(accAddrs[0], valAddrs[0], sdk.NewDec(100)),
+			stakingtypes.NewDelegation(accAddrs[1], valAddrs[0], sdk.NewDecWithPrec(2505, 1)),
+			stakingtypes.NewDelegation(accAddrs[0], valAddrs[1], sdk.NewDec(300)),
+			stakingtypes.NewDelegation(accAddrs[2], valAddrs[1], sdk.NewDec(1)),
+			stakingtypes.NewDelegation(accAddrs[1], valAddrs[1], sdk.NewDec(50)),
+		}
+		dir = t.TempDir()
+	)
+	bz, err := json.Marshal(delegs)
+	require.NoError(err)
+	require.NoError(os.WriteFile(filepath.Join(dir, "delegations.json"), bz, 0o666))
+	// Index the whole file decode, like parseDelegationsByAddr did before
+	// streaming the delegations.
+	var decoded []stakingtypes.Delegation
+	require.NoError(json.Unmarshal(bz, &decoded))
+	expected := make(map[string][]stakingtypes.Delegation)
+	for _, d := range decoded {
+		expected[d.DelegatorAddress] = append(expected[d.DelegatorAddress], d)
+	}
+
+	delegsByAddr, err := parseDelegationsByAddr(dataSource{dir: dir})
+
+	require.NoError(err)
+	assert.Equal(expected, delegsByAddr)
+	require.Len(delegsByAddr[accAddrs[1].String()], 2)
+	// order of the file is kept
+	assert.Equal(valAddrs[0].String(), delegsByAddr[accAddrs[1].String()][0].ValidatorAddress)
+	assert.Equal("250.500000000000000000", delegsByAddr[accAddrs[1].String()][0].Shares.String())
+	assert.Equal(valAddrs[1].String(), delegsByAddr[accAddrs[1].String()][1].ValidatorAddress)
+}
+
+func TestParseProp(t *testing.T) {
+	// missing prop.json is an error, not a panic
+	_, err := parseProp(dataSource{dir: t.TempDir()})
+	require.Error(t, err)
+
+	src := exportSource
+	src.propID = 1
+	_, err = parseProp(src)
+	require.EqualError(t, err, "proposal 1 not found in testdata/export-tally.json")
+}
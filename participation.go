@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	h "github.com/dustin/go-humanize"
+	"github.com/olekukonko/tablewriter"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+// participationStats are the number of delegators and the voting power of a
+// participation category.
+type participationStats struct {
+	Delegators  int     `json:"delegators"`
+	VotingPower sdk.Dec `json:"voting_power"`
+}
+
+// participationCounts splits the delegators by how they participated.
+type participationCounts struct {
+	// Direct are the delegators who voted.
+	Direct participationStats `json:"direct"`
+	// Inherited are the delegators who didn't vote, and delegate to at least
+	// one validator who voted. Only the voting power of the delegations to
+	// the validators who voted is counted, the rest is counted in Silent.
+	Inherited participationStats `json:"inherited"`
+	// Silent are the delegators who didn't vote, and whose validators didn't
+	// vote either.
+	Silent participationStats `json:"silent"`
+}
+
+func newParticipationCounts() participationCounts {
+	return participationCounts{
+		Direct:    participationStats{VotingPower: sdk.ZeroDec()},
+		Inherited: participationStats{VotingPower: sdk.ZeroDec()},
+		Silent:    participationStats{VotingPower: sdk.ZeroDec()},
+	}
+}
+
+// add counts a delegator, whose inheritedPower is the part of its stake
+// delegated to validators who voted.
+func (c *participationCounts) add(voted bool, stake, inheritedPower sdk.Dec) {
+	switch {
+	case voted:
+		c.Direct.Delegators++
+		c.Direct.VotingPower = c.Direct.VotingPower.Add(stake)
+	case inheritedPower.IsPositive():
+		c.Inherited.Delegators++
+		c.Inherited.VotingPower = c.Inherited.VotingPower.Add(inheritedPower)
+		c.Silent.VotingPower = c.Silent.VotingPower.Add(stake.Sub(inheritedPower))
+	default:
+		c.Silent.Delegators++
+		c.Silent.VotingPower = c.Silent.VotingPower.Add(stake)
+	}
+}
+
+// castVotingPower returns the voting power cast, directly or inherited.
+func (c participationCounts) castVotingPower() sdk.Dec {
+	return c.Direct.VotingPower.Add(c.Inherited.VotingPower)
+}
+
+// participationBuckets are the lower bounds of the stake size buckets, in the
+// base denom.
+var participationBuckets = []int64{
+	0, 1_000_000, 10_000_000, 100_000_000, 1_000_000_000, 10_000_000_000, 100_000_000_000,
+}
+
+// participationBucket is the participation of the delegators whose stake is
+// within [MinStake, MaxStake). MaxStake is nil for the last bucket.
+type participationBucket struct {
+	MinStake sdk.Int  `json:"min_stake"`
+	MaxStake *sdk.Int `json:"max_stake,omitempty"`
+	participationCounts
+	// Turnout is the ratio of the voting power cast over the voting power of
+	// the bucket.
+	Turnout sdk.Dec `json:"turnout"`
+}
+
+// participation reports who participated in the vote of a proposal.
+type participation struct {
+	participationCounts
+	Validators      int     `json:"validators"`
+	ValidatorsVoted int     `json:"validators_voted"`
+	TotalBonded     sdk.Int `json:"total_bonded"`
+	// BondedTurnout is the ratio of the voting power cast over the bonded
+	// tokens.
+	BondedTurnout sdk.Dec `json:"bonded_turnout"`
+	// TotalSupply and SupplyTurnout are nil when the dataset has no supply.
+	TotalSupply   *sdk.Int              `json:"total_supply,omitempty"`
+	SupplyTurnout *sdk.Dec              `json:"supply_turnout,omitempty"`
+	Buckets       []participationBucket `json:"buckets"`
+}
+
+// newParticipation computes the participation of the delegators of the active
+// validators, following the x/gov vote inheritance. totalSupply can be nil.
+func newParticipation(
+	votesByAddr map[string]govtypes.WeightedVoteOptions, valsByAddr map[string]govtypes.ValidatorGovInfo,
+	delegsByAddr map[string][]stakingtypes.Delegation, totalBonded sdk.Int, totalSupply *sdk.Int,
+) participation {
+	p := participation{
+		participationCounts: newParticipationCounts(),
+		Validators:          len(valsByAddr),
+		TotalBonded:         totalBonded,
+		BondedTurnout:       sdk.ZeroDec(),
+		TotalSupply:         totalSupply,
+	}
+	for i, minStake := range participationBuckets {
+		b := participationBucket{
+			MinStake:            sdk.NewInt(minStake),
+			participationCounts: newParticipationCounts(),
+			Turnout:             sdk.ZeroDec(),
+		}
+		if i+1 < len(participationBuckets) {
+			maxStake := sdk.NewInt(participationBuckets[i+1])
+			b.MaxStake = &maxStake
+		}
+		p.Buckets = append(p.Buckets, b)
+	}
+	for _, val := range valsByAddr {
+		if len(val.Vote) > 0 {
+			p.ValidatorsVoted++
+		}
+	}
+	for addr, delegs := range delegsByAddr {
+		var (
+			_, voted       = votesByAddr[addr]
+			stake          = sdk.ZeroDec()
+			inheritedPower = sdk.ZeroDec()
+		)
+		for _, deleg := range delegs {
+			val, ok := valsByAddr[deleg.ValidatorAddress]
+			if !ok {
+				// Validator isn't in active set or jailed, no voting power
+				continue
+			}
+			votingPower := deleg.GetShares().MulInt(val.BondedTokens).Quo(val.DelegatorShares)
+			stake = stake.Add(votingPower)
+			if len(val.Vote) > 0 {
+				inheritedPower = inheritedPower.Add(votingPower)
+			}
+		}
+		if stake.IsZero() {
+			continue
+		}
+		p.add(voted, stake, inheritedPower)
+		p.Buckets[bucketIndex(stake)].add(voted, stake, inheritedPower)
+	}
+	for i, b := range p.Buckets {
+		total := b.castVotingPower().Add(b.Silent.VotingPower)
+		if !total.IsZero() {
+			p.Buckets[i].Turnout = b.castVotingPower().Quo(total)
+		}
+	}
+	if !totalBonded.IsZero() {
+		p.BondedTurnout = p.castVotingPower().QuoInt(totalBonded)
+	}
+	if totalSupply != nil && !totalSupply.IsZero() {
+		supplyTurnout := p.castVotingPower().QuoInt(*totalSupply)
+		p.SupplyTurnout = &supplyTurnout
+	}
+	return p
+}
+
+// bucketIndex returns the index of the participation bucket of stake.
+func bucketIndex(stake sdk.Dec) int {
+	for i := len(participationBuckets) - 1; i > 0; i-- {
+		if stake.GTE(sdk.NewDec(participationBuckets[i])) {
+			return i
+		}
+	}
+	return 0
+}
+
+func percent(d sdk.Dec) string {
+	return fmt.Sprintf("%.2f%%", d.MustFloat64()*100)
+}
+
+func printParticipation(p participation) {
+	fmt.Println("--- PARTICIPATION ---")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"", "Delegators", "Voting power", "Of bonded"})
+	table.SetAutoWrapText(false)
+	for _, c := range []struct {
+		name  string
+		stats participationStats
+	}{
+		{"Direct voters", p.Direct},
+		{"Inherited voters", p.Inherited},
+		{"Silent", p.Silent},
+	} {
+		ofBonded := "-"
+		if !p.TotalBonded.IsZero() {
+			ofBonded = percent(c.stats.VotingPower.QuoInt(p.TotalBonded))
+		}
+		table.Append([]string{
+			c.name, h.Comma(int64(c.stats.Delegators)), human(c.stats.VotingPower.TruncateInt()), ofBonded,
+		})
+	}
+	table.Render()
+
+	totalSupply, supplyTurnout := "-", "-"
+	if p.TotalSupply != nil {
+		totalSupply = human(*p.TotalSupply)
+	}
+	if p.SupplyTurnout != nil {
+		supplyTurnout = percent(*p.SupplyTurnout)
+	}
+	table = tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"", "Value"})
+	table.SetAutoWrapText(false)
+	table.AppendBulk([][]string{
+		{"Validators voted", fmt.Sprintf("%d / %d", p.ValidatorsVoted, p.Validators)},
+		{"Total bonded", human(p.TotalBonded)},
+		{"Turnout of bonded", percent(p.BondedTurnout)},
+		{"Total supply", totalSupply},
+		{"Turnout of supply", supplyTurnout},
+	})
+	table.Render()
+
+	fmt.Println("--- PARTICIPATION BY STAKE ---")
+	table = tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{
+		"Stake", "Direct voters", "Inherited voters", "Silent delegators",
+		"Direct", "Inherited", "Silent", "Turnout",
+	})
+	table.SetAutoWrapText(false)
+	for _, b := range p.Buckets {
+		stake := human(b.MinStake) + "+"
+		if b.MaxStake != nil {
+			stake = human(b.MinStake) + "-" + human(*b.MaxStake)
+		}
+		table.Append([]string{
+			stake,
+			h.Comma(int64(b.Direct.Delegators)),
+			h.Comma(int64(b.Inherited.Delegators)),
+			h.Comma(int64(b.Silent.Delegators)),
+			human(b.Direct.VotingPower.TruncateInt()),
+			human(b.Inherited.VotingPower.TruncateInt()),
+			human(b.Silent.VotingPower.TruncateInt()),
+			percent(b.Turnout),
+		})
+	}
+	table.Render()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+func TestNewParticipation(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		accAddrs = createAccountAddrs(4)
+		valAddrs = createValidatorAddrs(2)
+		voteYes  = govtypes.WeightedVoteOptions{{Option: govtypes.OptionYes, Weight: sdk.OneDec()}}
+		// val0 voted, val1 didn't
+		valsByAddr = map[string]govtypes.ValidatorGovInfo{
+			valAddrs[0].String(): govtypes.NewValidatorGovInfo(valAddrs[0], sdk.NewInt(50_000_000), sdk.NewDec(50_000_000), sdk.ZeroDec(), voteYes),
+			valAddrs[1].String(): govtypes.NewValidatorGovInfo(valAddrs[1], sdk.NewInt(50_000_000), sdk.NewDec(50_000_000), sdk.ZeroDec(), nil),
+		}
+		delegsByAddr = map[string][]stakingtypes.Delegation{
+			// direct voter
+			accAddrs[0].String(): {stakingtypes.NewDelegation(accAddrs[0], valAddrs[1], sdk.NewDec(500_000))},
+			// inherited voter, with a silent part
+			accAddrs[1].String(): {
+				stakingtypes.NewDelegation(accAddrs[1], valAddrs[0], sdk.NewDec(30_000_000)),
+				stakingtypes.NewDelegation(accAddrs[1], valAddrs[1], sdk.NewDec(10_000_000)),
+			},
+			// silent
+			accAddrs[2].String(): {stakingtypes.NewDelegation(accAddrs[2], valAddrs[1], sdk.NewDec(2_000_000))},
+			// delegates to an inactive validator, no voting power
+			accAddrs[3].String(): {stakingtypes.NewDelegation(accAddrs[3], createValidatorAddrs(3)[2], sdk.NewDec(1_000_000))},
+		}
+		votesByAddr = map[string]govtypes.WeightedVoteOptions{
+			accAddrs[0].String(): voteYes,
+		}
+		totalSupply = sdk.NewInt(200_000_000)
+	)
+
+	p := newParticipation(votesByAddr, valsByAddr, delegsByAddr, sdk.NewInt(100_000_000), &totalSupply)
+
+	assert.Equal(2, p.Validators)
+	assert.Equal(1, p.ValidatorsVoted)
+	assert.Equal(1, p.Direct.Delegators)
+	assert.Equal(sdk.NewDec(500_000).String(), p.Direct.VotingPower.String())
+	assert.Equal(1, p.Inherited.Delegators)
+	assert.Equal(sdk.NewDec(30_000_000).String(), p.Inherited.VotingPower.String())
+	assert.Equal(1, p.Silent.Delegators)
+	assert.Equal(sdk.NewDec(12_000_000).String(), p.Silent.VotingPower.String())
+	assert.Equal(sdk.MustNewDecFromStr("0.305").String(), p.BondedTurnout.String())
+	assert.Equal(sdk.MustNewDecFromStr("0.1525").String(), p.SupplyTurnout.String())
+	// 0-1, 1-10 and 10-100 buckets
+	assert.Equal(1, p.Buckets[0].Direct.Delegators)
+	assert.Equal(sdk.OneDec().String(), p.Buckets[0].Turnout.String())
+	assert.Equal(1, p.Buckets[1].Silent.Delegators)
+	assert.True(p.Buckets[1].Turnout.IsZero())
+	assert.Equal(1, p.Buckets[2].Inherited.Delegators)
+	assert.Equal(sdk.MustNewDecFromStr("0.75").String(), p.Buckets[2].Turnout.String())
+	assert.Nil(p.Buckets[len(p.Buckets)-1].MaxStake)
+	// participation.json keys are snake case like the other reports
+	bz, err := json.Marshal(p)
+	require.NoError(t, err)
+	var keys map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(bz, &keys))
+	for _, key := range []string{"direct", "inherited", "silent", "validators_voted", "bonded_turnout", "buckets"} {
+		assert.Contains(keys, key)
+	}
+	assert.Contains(string(keys["buckets"]), `"min_stake":`)
+}
+
+func TestParticipationFromExport(t *testing.T) {
+	var (
+		require = require.New(t)
+		assert  = assert.New(t)
+		src     = exportSource
+	)
+	votesByAddr, _, err := parseVotesByAddr(src)
+	require.NoError(err)
+	valsByAddr, err := parseValidatorsByAddr(src, votesByAddr)
+	require.NoError(err)
+	delegsByAddr, err := parseDelegationsByAddr(src)
+	require.NoError(err)
+	totalBonded, err := parseTotalBondedTokens(src)
+	require.NoError(err)
+	totalSupply, err := parseBondDenomSupply(src)
+	require.NoError(err)
+	require.NotNil(totalSupply)
+	assert.Equal("26001300", totalSupply.String())
+
+	p := newParticipation(votesByAddr, valsByAddr, delegsByAddr, totalBonded, totalSupply)
+
+	// the voting power cast is the tally total voting power
+	res := tally(votesByAddr, valsByAddr, delegsByAddr)
+	assert.Equal(res.TotalVotingPower.String(), p.castVotingPower().String())
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+// rewardsCalculator computes the outstanding rewards of delegations from the
+// distribution module genesis, the same way the distribution module does when
+// the rewards are withdrawn.
+type rewardsCalculator struct {
+	// historicalRatios are the cumulative reward ratios per validator and
+	// period.
+	historicalRatios map[string]map[uint64]sdk.DecCoins
+	currentRewards   map[string]distrtypes.ValidatorCurrentRewards
+	// slashEvents are sorted by height, like the store iteration.
+	slashEvents map[string][]distrtypes.ValidatorSlashEventRecord
+	// startingInfos are indexed by validator then delegator.
+	startingInfos map[string]map[string]distrtypes.DelegatorStartingInfo
+}
+
+func newRewardsCalculator(genesis distrtypes.GenesisState) rewardsCalculator {
+	c := rewardsCalculator{
+		historicalRatios: make(map[string]map[uint64]sdk.DecCoins),
+		currentRewards:   make(map[string]distrtypes.ValidatorCurrentRewards),
+		slashEvents:      make(map[string][]distrtypes.ValidatorSlashEventRecord),
+		startingInfos:    make(map[string]map[string]distrtypes.DelegatorStartingInfo),
+	}
+	for _, r := range genesis.ValidatorHistoricalRewards {
+		if c.historicalRatios[r.ValidatorAddress] == nil {
+			c.historicalRatios[r.ValidatorAddress] = make(map[uint64]sdk.DecCoins)
+		}
+		c.historicalRatios[r.ValidatorAddress][r.Period] = r.Rewards.CumulativeRewardRatio
+	}
+	for _, r := range genesis.ValidatorCurrentRewards {
+		c.currentRewards[r.ValidatorAddress] = r.Rewards
+	}
+	for _, e := range genesis.ValidatorSlashEvents {
+		c.slashEvents[e.ValidatorAddress] = append(c.slashEvents[e.ValidatorAddress], e)
+	}
+	for _, events := range c.slashEvents {
+		sort.SliceStable(events, func(i, j int) bool {
+			if events[i].Height != events[j].Height {
+				return events[i].Height < events[j].Height
+			}
+			return events[i].Period < events[j].Period
+		})
+	}
+	for _, i := range genesis.DelegatorStartingInfos {
+		if c.startingInfos[i.ValidatorAddress] == nil {
+			c.startingInfos[i.ValidatorAddress] = make(map[string]distrtypes.DelegatorStartingInfo)
+		}
+		c.startingInfos[i.ValidatorAddress][i.DelegatorAddress] = i.StartingInfo
+	}
+	return c
+}
+
+// delegationRewards returns the outstanding rewards of the delegation, as
+// computed by the distribution module CalculateDelegationRewards, where the
+// validator period is incremented with the current rewards.
+func (c rewardsCalculator) delegationRewards(val stakingtypes.Validator, deleg stakingtypes.Delegation) (sdk.DecCoins, error) {
+	startingInfo, ok := c.startingInfos[val.OperatorAddress][deleg.DelegatorAddress]
+	if !ok {
+		return nil, fmt.Errorf("no starting info for delegation of %s to %s", deleg.DelegatorAddress, val.OperatorAddress)
+	}
+	var (
+		rewards        sdk.DecCoins
+		startingPeriod = startingInfo.PreviousPeriod
+		stake          = startingInfo.Stake
+	)
+	// Iterate through slashes that occurred since the delegation started
+	for _, e := range c.slashEvents[val.OperatorAddress] {
+		if e.Height < startingInfo.Height {
+			continue
+		}
+		endingPeriod := e.ValidatorSlashEvent.ValidatorPeriod
+		if endingPeriod > startingPeriod {
+			r, err := c.rewardsBetween(val.OperatorAddress, startingPeriod, endingPeriod, stake)
+			if err != nil {
+				return nil, err
+			}
+			rewards = rewards.Add(r...)
+			stake = stake.MulTruncate(sdk.OneDec().Sub(e.ValidatorSlashEvent.Fraction))
+			startingPeriod = endingPeriod
+		}
+	}
+	// Stake sanity check, tolerate the same rounding error as the module
+	currentStake := val.TokensFromShares(deleg.Shares)
+	if stake.GT(currentStake) {
+		marginOfErr := sdk.SmallestDec().MulInt64(3)
+		if stake.GT(currentStake.Add(marginOfErr)) {
+			return nil, fmt.Errorf("calculated final stake %s for delegator %s to %s greater than current stake %s",
+				stake, deleg.DelegatorAddress, val.OperatorAddress, currentStake)
+		}
+		stake = currentStake
+	}
+	// Rewards of the final period, which is the current period ended with the
+	// current rewards.
+	current, ok := c.currentRewards[val.OperatorAddress]
+	if !ok {
+		return nil, fmt.Errorf("no current rewards for validator %s", val.OperatorAddress)
+	}
+	endingRatio, err := c.ratio(val.OperatorAddress, current.Period-1)
+	if err != nil {
+		return nil, err
+	}
+	if !val.Tokens.IsZero() {
+		endingRatio = endingRatio.Add(current.Rewards.QuoDecTruncate(val.Tokens.ToDec())...)
+	}
+	startingRatio, err := c.ratio(val.OperatorAddress, startingPeriod)
+	if err != nil {
+		return nil, err
+	}
+	r, err := ratioRewards(startingRatio, endingRatio, stake)
+	if err != nil {
+		return nil, err
+	}
+	return rewards.Add(r...), nil
+}
+
+// rewardsBetween returns the rewards of stake between the two periods.
+func (c rewardsCalculator) rewardsBetween(valAddr string, startingPeriod, endingPeriod uint64, stake sdk.Dec) (sdk.DecCoins, error) {
+	startingRatio, err := c.ratio(valAddr, startingPeriod)
+	if err != nil {
+		return nil, err
+	}
+	endingRatio, err := c.ratio(valAddr, endingPeriod)
+	if err != nil {
+		return nil, err
+	}
+	return ratioRewards(startingRatio, endingRatio, stake)
+}
+
+func (c rewardsCalculator) ratio(valAddr string, period uint64) (sdk.DecCoins, error) {
+	ratio, ok := c.historicalRatios[valAddr][period]
+	if !ok {
+		return nil, fmt.Errorf("no historical rewards for validator %s period %d", valAddr, period)
+	}
+	return ratio, nil
+}
+
+// ratioRewards returns stake * (endingRatio - startingRatio).
+func ratioRewards(startingRatio, endingRatio sdk.DecCoins, stake sdk.Dec) (sdk.DecCoins, error) {
+	difference := endingRatio.Sub(startingRatio)
+	if difference.IsAnyNegative() {
+		return nil, fmt.Errorf("negative rewards between ratios %s and %s", startingRatio, endingRatio)
+	}
+	// Truncate to not count more rewards than owed
+	return difference.MulDecTruncate(stake), nil
+}
+
+// getRewardsByAddr returns the outstanding rewards per delegator. Like when
+// they are withdrawn, the rewards of each delegation are truncated. The rewards
+// of the tokenize share records are owned by the records owner.
+func getRewardsByAddr(
+	calc rewardsCalculator,
+	vals []stakingtypes.Validator,
+	delegsByAddr map[string][]stakingtypes.Delegation,
+	shareRecords []stakingtypes.TokenizeShareRecord,
+) (map[string]sdk.Coins, error) {
+	valsByAddr := make(map[string]stakingtypes.Validator, len(vals))
+	for _, val := range vals {
+		valsByAddr[val.OperatorAddress] = val
+	}
+	ownersByAddr := make(map[string]string, len(shareRecords))
+	for _, r := range shareRecords {
+		ownersByAddr[r.GetModuleAddress().String()] = r.Owner
+	}
+	rewardsByAddr := make(map[string]sdk.Coins)
+	for addr, delegs := range delegsByAddr {
+		for _, deleg := range delegs {
+			val, ok := valsByAddr[deleg.ValidatorAddress]
+			if !ok {
+				return nil, fmt.Errorf("validator %s not found", deleg.ValidatorAddress)
+			}
+			rewards, err := calc.delegationRewards(val, deleg)
+			if err != nil {
+				return nil, err
+			}
+			coins, _ := rewards.TruncateDecimal()
+			if coins.IsZero() {
+				continue
+			}
+			owner := addr
+			if o, ok := ownersByAddr[addr]; ok {
+				owner = o
+			}
+			rewardsByAddr[owner] = rewardsByAddr[owner].Add(coins...)
+		}
+	}
+	return rewardsByAddr, nil
+}
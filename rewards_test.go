@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+func TestDelegationRewards(t *testing.T) {
+	var (
+		valAddr = "cosmosvaloper1srjwwypstmwuf7s77d9whuj060q9xjafenflmz"
+		delAddr = "cosmos19mmzdrgpjwvwmq3vx6zqnrqdfxt4grvkvvxlps"
+		val     = stakingtypes.Validator{
+			OperatorAddress: valAddr,
+			Tokens:          sdk.NewInt(1000),
+			DelegatorShares: sdk.NewDec(1000),
+		}
+		deleg = stakingtypes.Delegation{
+			DelegatorAddress: delAddr,
+			ValidatorAddress: valAddr,
+			Shares:           sdk.NewDec(100),
+		}
+		ratio = func(amount string) sdk.DecCoins {
+			return sdk.NewDecCoins(sdk.NewDecCoinFromDec("uatom", sdk.MustNewDecFromStr(amount)))
+		}
+		genesis = func(stake sdk.Dec) distrtypes.GenesisState {
+			return distrtypes.GenesisState{
+				ValidatorHistoricalRewards: []distrtypes.ValidatorHistoricalRewardsRecord{
+					{ValidatorAddress: valAddr, Period: 1, Rewards: distrtypes.ValidatorHistoricalRewards{CumulativeRewardRatio: ratio("0.5")}},
+					{ValidatorAddress: valAddr, Period: 2, Rewards: distrtypes.ValidatorHistoricalRewards{CumulativeRewardRatio: ratio("1.5")}},
+				},
+				ValidatorCurrentRewards: []distrtypes.ValidatorCurrentRewardsRecord{
+					{ValidatorAddress: valAddr, Rewards: distrtypes.ValidatorCurrentRewards{Rewards: ratio("500"), Period: 3}},
+				},
+				DelegatorStartingInfos: []distrtypes.DelegatorStartingInfoRecord{
+					{
+						DelegatorAddress: delAddr,
+						ValidatorAddress: valAddr,
+						StartingInfo:     distrtypes.DelegatorStartingInfo{PreviousPeriod: 1, Stake: stake, Height: 10},
+					},
+				},
+			}
+		}
+	)
+	tests := []struct {
+		name            string
+		genesis         distrtypes.GenesisState
+		expectedRewards string
+		expectedErr     string
+	}{
+		{
+			name: "ok",
+			// (1.5 + 500/1000 - 0.5) * 100
+			genesis:         genesis(sdk.NewDec(100)),
+			expectedRewards: "150.000000000000000000uatom",
+		},
+		{
+			name:            "stake within margin of error",
+			genesis:         genesis(sdk.NewDec(100).Add(sdk.SmallestDec())),
+			expectedRewards: "150.000000000000000000uatom",
+		},
+		{
+			name:        "stake greater than current stake",
+			genesis:     genesis(sdk.NewDec(101)),
+			expectedErr: "calculated final stake 101.000000000000000000 for delegator cosmos19mmzdrgpjwvwmq3vx6zqnrqdfxt4grvkvvxlps to cosmosvaloper1srjwwypstmwuf7s77d9whuj060q9xjafenflmz greater than current stake 100.000000000000000000",
+		},
+		{
+			name:        "no starting info",
+			genesis:     distrtypes.GenesisState{},
+			expectedErr: "no starting info for delegation of cosmos19mmzdrgpjwvwmq3vx6zqnrqdfxt4grvkvvxlps to cosmosvaloper1srjwwypstmwuf7s77d9whuj060q9xjafenflmz",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rewards, err := newRewardsCalculator(tt.genesis).delegationRewards(val, deleg)
+
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedRewards, rewards.String())
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+// tallyRule is a set of governance rules, which decides how the votes are
+// tallied and how the proposal is resolved.
+type tallyRule interface {
+	tally(
+		votesByAddr map[string]govtypes.WeightedVoteOptions, valsByAddr map[string]govtypes.ValidatorGovInfo,
+		delegsByAddr map[string][]stakingtypes.Delegation,
+	) tallyResult
+	outcome(res tallyResult, totalBonded sdk.Int) tallyOutcome
+	// tallyParams returns the tally params applied by outcome.
+	tallyParams() govtypes.TallyParams
+}
+
+// tallyRules are the constructors of the tally rules, by name.
+var tallyRules = map[string]func(params govtypes.TallyParams) tallyRule{
+	"hub": func(params govtypes.TallyParams) tallyRule {
+		return hubRule{params: params}
+	},
+	"no-inheritance": func(params govtypes.TallyParams) tallyRule {
+		return noInheritanceRule{params: params}
+	},
+}
+
+// tallyRuleNames returns the sorted names of the tally rules.
+func tallyRuleNames() string {
+	names := make([]string, 0, len(tallyRules))
+	for name := range tallyRules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// newTallyRule returns the tally rule named name. If not nil, threshold
+// replaces the threshold of the tally params, and must be in (0,1].
+func newTallyRule(name string, params govtypes.TallyParams, threshold *sdk.Dec) (tallyRule, error) {
+	newRule, ok := tallyRules[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown tally rule %q, available rules: %s", name, tallyRuleNames())
+	}
+	if threshold != nil {
+		if !threshold.IsPositive() || threshold.GT(sdk.OneDec()) {
+			return nil, fmt.Errorf("invalid threshold %s, it must be greater than 0 and at most 1", threshold)
+		}
+		params.Threshold = *threshold
+	}
+	return newRule(params), nil
+}
+
+// hubRule is the Cosmos Hub v1beta1 governance: delegators who didn't vote
+// inherit the vote of their validators.
+type hubRule struct {
+	params govtypes.TallyParams
+}
+
+func (r hubRule) tally(
+	votesByAddr map[string]govtypes.WeightedVoteOptions, valsByAddr map[string]govtypes.ValidatorGovInfo,
+	delegsByAddr map[string][]stakingtypes.Delegation,
+) tallyResult {
+	return tally(votesByAddr, valsByAddr, delegsByAddr)
+}
+
+func (r hubRule) outcome(res tallyResult, totalBonded sdk.Int) tallyOutcome {
+	return newTallyOutcome(res, totalBonded, r.params)
+}
+
+func (r hubRule) tallyParams() govtypes.TallyParams { return r.params }
+
+// noInheritanceRule is a governance without vote inheritance, like proposed
+// by AtomOne: only the delegators who voted count, validators only vote with
+// their own delegations. The threshold is usually a supermajority.
+type noInheritanceRule struct {
+	params govtypes.TallyParams
+}
+
+func (r noInheritanceRule) tally(
+	votesByAddr map[string]govtypes.WeightedVoteOptions, valsByAddr map[string]govtypes.ValidatorGovInfo,
+	delegsByAddr map[string][]stakingtypes.Delegation,
+) tallyResult {
+	return tallyVotes(votesByAddr, valsByAddr, delegsByAddr, false)
+}
+
+func (r noInheritanceRule) outcome(res tallyResult, totalBonded sdk.Int) tallyOutcome {
+	return newTallyOutcome(res, totalBonded, r.params)
+}
+
+func (r noInheritanceRule) tallyParams() govtypes.TallyParams { return r.params }
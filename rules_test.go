@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+func TestTallyRules(t *testing.T) {
+	var (
+		accAddrs = createAccountAddrs(2)
+		valAddr  = createValidatorAddrs(1)[0]
+		voteYes  = govtypes.WeightedVoteOptions{{Option: govtypes.OptionYes, Weight: sdk.OneDec()}}
+		voteNo   = govtypes.WeightedVoteOptions{{Option: govtypes.OptionNo, Weight: sdk.OneDec()}}
+		// the validator account self-delegates 100 tokens
+		valAcc     = sdk.AccAddress(valAddr)
+		valsByAddr = map[string]govtypes.ValidatorGovInfo{
+			valAddr.String(): govtypes.NewValidatorGovInfo(valAddr, sdk.NewInt(1000), sdk.NewDec(1000), sdk.ZeroDec(), voteYes),
+		}
+		delegsByAddr = map[string][]stakingtypes.Delegation{
+			accAddrs[0].String(): {stakingtypes.NewDelegation(accAddrs[0], valAddr, sdk.NewDec(200))},
+			accAddrs[1].String(): {stakingtypes.NewDelegation(accAddrs[1], valAddr, sdk.NewDec(700))},
+			valAcc.String():      {stakingtypes.NewDelegation(valAcc, valAddr, sdk.NewDec(100))},
+		}
+		votesByAddr = map[string]govtypes.WeightedVoteOptions{
+			accAddrs[0].String(): voteNo,
+			valAcc.String():      voteYes,
+		}
+		params      = govtypes.NewTallyParams(sdk.NewDecWithPrec(1, 1), sdk.NewDecWithPrec(5, 1), sdk.NewDecWithPrec(334, 3))
+		totalBonded = sdk.NewInt(1000)
+		twoThirds   = sdk.NewDecWithPrec(667, 3)
+	)
+	tests := []struct {
+		name              string
+		rule              string
+		threshold         *sdk.Dec
+		expectedYes       int64
+		expectedNo        int64
+		expectedThreshold sdk.Dec
+		expectedPasses    bool
+	}{
+		{
+			name: "hub",
+			rule: "hub",
+			// validator votes with its self-delegation and accAddrs[1]
+			expectedYes:       800,
+			expectedNo:        200,
+			expectedThreshold: params.Threshold,
+			expectedPasses:    true,
+		},
+		{
+			name:              "no inheritance",
+			rule:              "no-inheritance",
+			expectedYes:       100,
+			expectedNo:        200,
+			expectedThreshold: params.Threshold,
+			expectedPasses:    false,
+		},
+		{
+			name:              "hub with supermajority",
+			rule:              "hub",
+			threshold:         &twoThirds,
+			expectedYes:       800,
+			expectedNo:        200,
+			expectedThreshold: twoThirds,
+			expectedPasses:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := newTallyRule(tt.rule, params, tt.threshold)
+			require.NoError(t, err)
+
+			res := rule.tally(votesByAddr, valsByAddr, delegsByAddr)
+			o := rule.outcome(res, totalBonded)
+
+			assert.Equal(t, sdk.NewDec(tt.expectedYes).String(), res.Results[govtypes.OptionYes].String())
+			assert.Equal(t, sdk.NewDec(tt.expectedNo).String(), res.Results[govtypes.OptionNo].String())
+			assert.Equal(t, tt.expectedThreshold.String(), rule.tallyParams().Threshold.String())
+			assert.Equal(t, tt.expectedPasses, o.Passes)
+		})
+	}
+
+	_, err := newTallyRule("unknown", params, nil)
+	require.EqualError(t, err, `unknown tally rule "unknown", available rules: hub, no-inheritance`)
+	for _, threshold := range []sdk.Dec{sdk.ZeroDec(), sdk.NewDec(-1), sdk.NewDecWithPrec(11, 1)} {
+		_, err = newTallyRule("hub", params, &threshold)
+		require.EqualError(t, err, fmt.Sprintf("invalid threshold %s, it must be greater than 0 and at most 1", threshold))
+	}
+	one := sdk.OneDec()
+	_, err = newTallyRule("hub", params, &one)
+	require.NoError(t, err)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+// voteOverrides are the changes of votes applied by the simulate command.
+// Votes are strings like "yes" or "yes=0.6,no=0.4".
+type voteOverrides struct {
+	// Votes are the new votes by voter address. A validator operator address
+	// can be used for the vote of a validator. A null vote removes the vote.
+	Votes map[string]*string `json:"votes"`
+	// NonVoters, if set, is the vote of every delegator and validator that
+	// hasn't voted, once Votes are applied.
+	NonVoters string `json:"non_voters"`
+}
+
+func parseVoteOverrides(path string) (voteOverrides, error) {
+	f, err := openFile(path)
+	if err != nil {
+		return voteOverrides{}, err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	var o voteOverrides
+	if err := dec.Decode(&o); err != nil {
+		return voteOverrides{}, fmt.Errorf("cannot json decode %s: %w", path, err)
+	}
+	return o, nil
+}
+
+// parseVote parses a vote like "yes" or "yes=0.6,no=0.4". Options can be
+// yes, no, no_with_veto, abstain or their VOTE_OPTION_ names. Like on chain,
+// each option appears once, with a weight in (0,1], and the weights sum to 1.
+func parseVote(s string) (govtypes.WeightedVoteOptions, error) {
+	var (
+		vote        govtypes.WeightedVoteOptions
+		totalWeight = sdk.ZeroDec()
+		entries     = strings.Split(s, ",")
+		seen        = make(map[govtypes.VoteOption]bool)
+	)
+	for _, entry := range entries {
+		name, weightStr, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found && len(entries) > 1 {
+			return nil, fmt.Errorf("missing weight for option %s in vote %q", name, s)
+		}
+		name = strings.ToUpper(name)
+		if !strings.HasPrefix(name, "VOTE_OPTION_") {
+			name = "VOTE_OPTION_" + name
+		}
+		option, err := govtypes.VoteOptionFromString(name)
+		if err != nil || !govtypes.ValidVoteOption(option) {
+			return nil, fmt.Errorf("invalid option in vote %q", s)
+		}
+		weight := sdk.OneDec()
+		if found {
+			weight, err = sdk.NewDecFromStr(weightStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid weight in vote %q: %w", s, err)
+			}
+		}
+		if seen[option] {
+			return nil, fmt.Errorf("duplicate option %s in vote %q", option, s)
+		}
+		seen[option] = true
+		wo := govtypes.WeightedVoteOption{Option: option, Weight: weight}
+		if !govtypes.ValidWeightedVoteOption(wo) {
+			return nil, fmt.Errorf("invalid weight %s of option %s in vote %q, it must be greater than 0 and at most 1",
+				weight, option, s)
+		}
+		vote = append(vote, wo)
+		totalWeight = totalWeight.Add(weight)
+	}
+	if !totalWeight.Equal(sdk.OneDec()) {
+		return nil, fmt.Errorf("weights of vote %q don't sum to 1", s)
+	}
+	return vote, nil
+}
+
+// applyVoteOverrides returns the votes and the validators with the overrides
+// applied. The arguments are not modified.
+func applyVoteOverrides(
+	overrides voteOverrides,
+	votesByAddr map[string]govtypes.WeightedVoteOptions,
+	valsByAddr map[string]govtypes.ValidatorGovInfo,
+	delegsByAddr map[string][]stakingtypes.Delegation,
+) (map[string]govtypes.WeightedVoteOptions, map[string]govtypes.ValidatorGovInfo, error) {
+	newVotesByAddr := make(map[string]govtypes.WeightedVoteOptions, len(votesByAddr))
+	for addr, vote := range votesByAddr {
+		newVotesByAddr[addr] = vote
+	}
+	for addr, voteStr := range overrides.Votes {
+		if valAddr, err := sdk.ValAddressFromBech32(addr); err == nil {
+			// Validator votes are cast by their account
+			addr = sdk.AccAddress(valAddr).String()
+		} else if _, err := sdk.AccAddressFromBech32(addr); err != nil {
+			return nil, nil, fmt.Errorf("invalid voter address %s: %w", addr, err)
+		}
+		if voteStr == nil {
+			delete(newVotesByAddr, addr)
+			continue
+		}
+		vote, err := parseVote(*voteStr)
+		if err != nil {
+			return nil, nil, err
+		}
+		newVotesByAddr[addr] = vote
+	}
+	if overrides.NonVoters != "" {
+		vote, err := parseVote(overrides.NonVoters)
+		if err != nil {
+			return nil, nil, err
+		}
+		nonVoters := make(map[string]bool)
+		for addr := range delegsByAddr {
+			nonVoters[addr] = true
+		}
+		for _, val := range valsByAddr {
+			nonVoters[sdk.AccAddress(val.Address).String()] = true
+		}
+		for addr := range nonVoters {
+			if _, ok := newVotesByAddr[addr]; !ok {
+				newVotesByAddr[addr] = vote
+			}
+		}
+	}
+	return newVotesByAddr, withValidatorVotes(valsByAddr, newVotesByAddr), nil
+}
+
+func printSimulation(w io.Writer, baseline, simulated tallyResult, baselineOutcome, simulatedOutcome tallyOutcome) {
+	printTallyComparison(w, "SIMULATION", "Baseline", "Simulated", baseline, simulated, baselineOutcome, simulatedOutcome)
+}
+
+// printTallyComparison prints the baseline and simulated tallies side by side,
+// under title and with their column labels.
+func printTallyComparison(
+	w io.Writer, title, baselineLabel, simulatedLabel string,
+	baseline, simulated tallyResult, baselineOutcome, simulatedOutcome tallyOutcome,
+) {
+	fmt.Fprintf(w, "--- %s ---\n", title)
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"", baselineLabel, simulatedLabel, "Diff"})
+	table.SetAutoWrapText(false)
+	for _, option := range []govtypes.VoteOption{
+		govtypes.OptionYes, govtypes.OptionNo, govtypes.OptionNoWithVeto, govtypes.OptionAbstain,
+	} {
+		b := baseline.Results[option].TruncateInt()
+		s := simulated.Results[option].TruncateInt()
+		table.Append([]string{option.String(), human(b), human(s), human(s.Sub(b))})
+	}
+	b := baseline.TotalVotingPower.TruncateInt()
+	s := simulated.TotalVotingPower.TruncateInt()
+	table.Append([]string{"Total", human(b), human(s), human(s.Sub(b))})
+	table.Append([]string{
+		"Participation",
+		baselineOutcome.Participation.String(),
+		simulatedOutcome.Participation.String(),
+		simulatedOutcome.Participation.Sub(baselineOutcome.Participation).String(),
+	})
+	appendBool := func(name string, b, s bool) {
+		diff := ""
+		if b != s {
+			diff = "changed"
+		}
+		table.Append([]string{name, fmt.Sprint(b), fmt.Sprint(s), diff})
+	}
+	appendBool("Quorum reached", baselineOutcome.QuorumReached, simulatedOutcome.QuorumReached)
+	appendBool("Veto triggered", baselineOutcome.Vetoed, simulatedOutcome.Vetoed)
+	appendBool("Passes", baselineOutcome.Passes, simulatedOutcome.Passes)
+	appendBool("Burn deposits", baselineOutcome.BurnDeposits, simulatedOutcome.BurnDeposits)
+	table.Render()
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+func TestParseVote(t *testing.T) {
+	tests := []struct {
+		vote         string
+		expectedVote string
+		expectedErr  string
+	}{
+		{vote: "yes", expectedVote: "VOTE_OPTION_YES"},
+		{vote: "NO_WITH_VETO", expectedVote: "VOTE_OPTION_NO_WITH_VETO"},
+		{vote: "VOTE_OPTION_ABSTAIN", expectedVote: "VOTE_OPTION_ABSTAIN"},
+		{vote: "yes=0.6, no=0.4", expectedVote: "VOTE_OPTION_YES:0.600000000000000000,VOTE_OPTION_NO:0.400000000000000000"},
+		{vote: "yes=0.6,no", expectedErr: `missing weight for option no in vote "yes=0.6,no"`},
+		{vote: "maybe", expectedErr: `invalid option in vote "maybe"`},
+		{vote: "unspecified", expectedErr: `invalid option in vote "unspecified"`},
+		{vote: "yes=0.6,no=0.6", expectedErr: `weights of vote "yes=0.6,no=0.6" don't sum to 1`},
+		{
+			vote:        "yes=1.5,no=-0.5",
+			expectedErr: `invalid weight 1.500000000000000000 of option VOTE_OPTION_YES in vote "yes=1.5,no=-0.5", it must be greater than 0 and at most 1`,
+		},
+		{
+			vote:        "yes=1,no=0",
+			expectedErr: `invalid weight 0.000000000000000000 of option VOTE_OPTION_NO in vote "yes=1,no=0", it must be greater than 0 and at most 1`,
+		},
+		{vote: "yes=0.5,yes=0.5", expectedErr: `duplicate option VOTE_OPTION_YES in vote "yes=0.5,yes=0.5"`},
+		{vote: "yes=0.5,VOTE_OPTION_YES=0.5", expectedErr: `duplicate option VOTE_OPTION_YES in vote "yes=0.5,VOTE_OPTION_YES=0.5"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.vote, func(t *testing.T) {
+			vote, err := parseVote(tt.vote)
+
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedVote, voteString(vote))
+		})
+	}
+}
+
+func TestApplyVoteOverrides(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		require  = require.New(t)
+		accAddrs = createAccountAddrs(3)
+		valAddr  = createValidatorAddrs(1)[0]
+		valAcc   = sdk.AccAddress(valAddr).String()
+		acc0     = accAddrs[0].String()
+		acc1     = accAddrs[1].String()
+		acc2     = accAddrs[2].String()
+		voteYes  = govtypes.WeightedVoteOptions{{Option: govtypes.OptionYes, Weight: sdk.OneDec()}}
+		voteNo   = "no"
+		votes    = map[string]govtypes.WeightedVoteOptions{
+			valAcc: voteYes,
+			acc0:   voteYes,
+			acc1:   voteYes,
+		}
+		vals = map[string]govtypes.ValidatorGovInfo{
+			valAddr.String(): govtypes.NewValidatorGovInfo(valAddr, sdk.NewInt(100), sdk.NewDec(100), sdk.ZeroDec(), voteYes),
+		}
+		delegs = map[string][]stakingtypes.Delegation{
+			acc0: {stakingtypes.NewDelegation(accAddrs[0], valAddr, sdk.NewDec(10))},
+			acc2: {stakingtypes.NewDelegation(accAddrs[2], valAddr, sdk.NewDec(10))},
+		}
+		overrides = voteOverrides{
+			Votes: map[string]*string{
+				valAddr.String(): &voteNo,
+				acc1:             nil,
+			},
+			NonVoters: "abstain",
+		}
+	)
+
+	newVotes, newVals, err := applyVoteOverrides(overrides, votes, vals, delegs)
+
+	require.NoError(err)
+	// validator vote overridden with its operator address
+	assert.Equal("VOTE_OPTION_NO", voteString(newVotes[valAcc]))
+	assert.Equal("VOTE_OPTION_NO", voteString(newVals[valAddr.String()].Vote))
+	assert.Equal("VOTE_OPTION_YES", voteString(newVotes[acc0]))
+	// removed vote, acc1 has no delegations so it's not a non-voter
+	assert.NotContains(newVotes, acc1)
+	assert.Equal("VOTE_OPTION_ABSTAIN", voteString(newVotes[acc2]))
+	// arguments are left untouched
+	assert.Len(votes, 3)
+	assert.Equal("VOTE_OPTION_YES", voteString(vals[valAddr.String()].Vote))
+
+	_, _, err = applyVoteOverrides(voteOverrides{Votes: map[string]*string{"foo": &voteNo}}, votes, vals, delegs)
+	assert.ErrorContains(err, "invalid voter address foo")
+}
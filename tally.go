@@ -2,7 +2,8 @@ package main
 
 import (
 	"fmt"
-	"os"
+	"io"
+	"sort"
 
 	h "github.com/dustin/go-humanize"
 	"github.com/olekukonko/tablewriter"
@@ -12,24 +13,75 @@ import (
 	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
 )
 
+// tallyResult is the result of a tally.
+type tallyResult struct {
+	// Results are the voting power per vote option.
+	Results          map[govtypes.VoteOption]sdk.Dec
+	TotalVotingPower sdk.Dec
+	// Validators is the breakdown of the tally per validator address.
+	Validators map[string]validatorTally
+	// Voters is the voting power cast per vote option by the delegators who
+	// voted, by address.
+	Voters map[string]map[govtypes.VoteOption]sdk.Dec
+}
+
+// validatorTally is the part of the tally of a validator.
+type validatorTally struct {
+	Address      string                       `json:"address"`
+	BondedTokens sdk.Int                      `json:"bonded_tokens"`
+	Vote         govtypes.WeightedVoteOptions `json:"vote"`
+	// DelegatorDeductions are the shares of the delegators who voted, which
+	// are deducted from the validator voting power.
+	DelegatorDeductions sdk.Dec `json:"delegator_deductions"`
+	// OverridingDelegators is the number of delegators who voted.
+	OverridingDelegators int `json:"overriding_delegators"`
+	// VotingPower is the voting power cast by the validator per vote option.
+	VotingPower map[govtypes.VoteOption]sdk.Dec `json:"voting_power"`
+	// TotalVotingPower is the voting power cast by the validator.
+	TotalVotingPower sdk.Dec `json:"total_voting_power"`
+}
+
+// tally computes the tally like x/gov Keeper.Tally. The arguments are not
+// modified, so tally can be called several times on the same data. The
+// DelegatorDeductions of the validators are ignored, the deductions are
+// computed from the votes.
 func tally(
 	votesByAddr map[string]govtypes.WeightedVoteOptions, valsByAddr map[string]govtypes.ValidatorGovInfo,
 	delegsByAddr map[string][]stakingtypes.Delegation,
-) (map[govtypes.VoteOption]sdk.Dec, sdk.Dec) {
-	var (
-		results = map[govtypes.VoteOption]sdk.Dec{
+) tallyResult {
+	return tallyVotes(votesByAddr, valsByAddr, delegsByAddr, true)
+}
+
+// tallyVotes computes the tally, where the validators votes are inherited by
+// the delegators who didn't vote if inherit is true.
+func tallyVotes(
+	votesByAddr map[string]govtypes.WeightedVoteOptions, valsByAddr map[string]govtypes.ValidatorGovInfo,
+	delegsByAddr map[string][]stakingtypes.Delegation, inherit bool,
+) tallyResult {
+	res := tallyResult{
+		Results: map[govtypes.VoteOption]sdk.Dec{
 			govtypes.OptionYes:        sdk.ZeroDec(),
 			govtypes.OptionAbstain:    sdk.ZeroDec(),
 			govtypes.OptionNo:         sdk.ZeroDec(),
 			govtypes.OptionNoWithVeto: sdk.ZeroDec(),
+		},
+		TotalVotingPower: sdk.ZeroDec(),
+		Validators:       make(map[string]validatorTally, len(valsByAddr)),
+		Voters:           make(map[string]map[govtypes.VoteOption]sdk.Dec, len(votesByAddr)),
+	}
+	for valAddr, val := range valsByAddr {
+		res.Validators[valAddr] = validatorTally{
+			Address:             valAddr,
+			BondedTokens:        val.BondedTokens,
+			Vote:                val.Vote,
+			DelegatorDeductions: sdk.ZeroDec(),
+			VotingPower:         newVoteMap(),
+			TotalVotingPower:    sdk.ZeroDec(),
 		}
-		totalVotingPower = sdk.ZeroDec()
-	)
+	}
 	for voterAddr, vote := range votesByAddr {
 		// Check voter delegations
 		dels := delegsByAddr[voterAddr]
-		// Initialize voter balance
-		// balance := sdk.NewDec(0)
 		for _, del := range dels {
 			val, ok := valsByAddr[del.ValidatorAddress]
 			if !ok {
@@ -37,65 +89,242 @@ func tally(
 				continue
 			}
 			// Reduce validator voting power with delegation that has voted
-			val.DelegatorDeductions = val.DelegatorDeductions.Add(del.GetShares())
-			valsByAddr[del.ValidatorAddress] = val
+			valTally := res.Validators[del.ValidatorAddress]
+			valTally.DelegatorDeductions = valTally.DelegatorDeductions.Add(del.GetShares())
+			valTally.OverridingDelegators++
+			res.Validators[del.ValidatorAddress] = valTally
 
 			// delegation shares * bonded / total shares
 			votingPower := del.GetShares().MulInt(val.BondedTokens).Quo(val.DelegatorShares)
+			if res.Voters[voterAddr] == nil {
+				res.Voters[voterAddr] = newVoteMap()
+			}
 			// Iterate over vote options
 			for _, option := range vote {
 				subPower := votingPower.Mul(option.Weight)
-				results[option.Option] = results[option.Option].Add(subPower)
+				res.Results[option.Option] = res.Results[option.Option].Add(subPower)
+				res.Voters[voterAddr][option.Option] = res.Voters[voterAddr][option.Option].Add(subPower)
 			}
-			totalVotingPower = totalVotingPower.Add(votingPower)
+			res.TotalVotingPower = res.TotalVotingPower.Add(votingPower)
 		}
 	}
+	if !inherit {
+		return res
+	}
 	// iterate over the validators again to tally their voting power
-	for _, val := range valsByAddr {
+	for valAddr, val := range valsByAddr {
 		if len(val.Vote) == 0 {
 			continue
 		}
-		sharesAfterDeductions := val.DelegatorShares.Sub(val.DelegatorDeductions)
+		valTally := res.Validators[valAddr]
+		sharesAfterDeductions := val.DelegatorShares.Sub(valTally.DelegatorDeductions)
 		votingPower := sharesAfterDeductions.MulInt(val.BondedTokens).Quo(val.DelegatorShares)
 
 		for _, option := range val.Vote {
 			subPower := votingPower.Mul(option.Weight)
-			results[option.Option] = results[option.Option].Add(subPower)
+			res.Results[option.Option] = res.Results[option.Option].Add(subPower)
+			valTally.VotingPower[option.Option] = valTally.VotingPower[option.Option].Add(subPower)
 		}
-		totalVotingPower = totalVotingPower.Add(votingPower)
+		res.TotalVotingPower = res.TotalVotingPower.Add(votingPower)
+		valTally.TotalVotingPower = votingPower
+		res.Validators[valAddr] = valTally
 	}
-	return results, totalVotingPower
+	return res
 }
 
-func printTallyResults(results map[govtypes.VoteOption]sdk.Dec, totalVotingPower sdk.Dec, prop govtypes.Proposal) {
-	fmt.Println("Computed total voting power", h.Comma(totalVotingPower.TruncateInt64()))
-	yesPercent := results[govtypes.OptionYes].
-		Quo(totalVotingPower.Sub(results[govtypes.OptionAbstain]))
-	fmt.Println("Yes percent:", yesPercent)
-	tallyResult := govtypes.NewTallyResultFromMap(results)
+// withValidatorVotes returns a copy of valsByAddr, where the validator votes
+// are the votes of their account in votesByAddr.
+func withValidatorVotes(
+	valsByAddr map[string]govtypes.ValidatorGovInfo, votesByAddr map[string]govtypes.WeightedVoteOptions,
+) map[string]govtypes.ValidatorGovInfo {
+	newValsByAddr := make(map[string]govtypes.ValidatorGovInfo, len(valsByAddr))
+	for valAddr, val := range valsByAddr {
+		val.Vote = votesByAddr[sdk.AccAddress(val.Address).String()]
+		newValsByAddr[valAddr] = val
+	}
+	return newValsByAddr
+}
 
-	fmt.Println("--- TALLY RESULT ---")
-	table := tablewriter.NewWriter(os.Stdout)
+func printTallyResults(w io.Writer, res tallyResult, prop govtypes.Proposal) {
+	fmt.Fprintln(w, "Computed total voting power", h.Comma(res.TotalVotingPower.TruncateInt64()))
+	yesPercent := res.Results[govtypes.OptionYes].
+		Quo(res.TotalVotingPower.Sub(res.Results[govtypes.OptionAbstain]))
+	fmt.Fprintln(w, "Yes percent:", yesPercent)
+	fmt.Fprintln(w, "--- TALLY RESULT ---")
+	table := tablewriter.NewWriter(w)
 	table.SetHeader([]string{"", "Yes", "No", "NoWithVeto", "Abstain", "Total"})
-	appendTable := func(source string, t govtypes.TallyResult) {
-		total := t.Yes.Add(t.No).Add(t.Abstain).Add(t.NoWithVeto)
+	for _, r := range newTallyRows(res, prop) {
 		table.Append([]string{
-			source,
-			human(t.Yes),
-			human(t.No),
-			human(t.NoWithVeto),
-			human(t.Abstain),
-			human(total),
+			r.Source,
+			human(r.Yes),
+			human(r.No),
+			human(r.NoWithVeto),
+			human(r.Abstain),
+			human(r.Total),
 		})
 	}
-	appendTable("computed", tallyResult)
-	appendTable("from prop", prop.FinalTallyResult)
-	diff := govtypes.NewTallyResult(
-		tallyResult.Yes.Sub(prop.FinalTallyResult.Yes),
-		tallyResult.Abstain.Sub(prop.FinalTallyResult.Abstain),
-		tallyResult.No.Sub(prop.FinalTallyResult.No),
-		tallyResult.NoWithVeto.Sub(prop.FinalTallyResult.NoWithVeto),
+	table.Render()
+}
+
+// tallyMismatch is a vote option where the computed tally differs from the
+// prop final tally.
+type tallyMismatch struct {
+	Option   govtypes.VoteOption
+	Computed sdk.Int
+	FromProp sdk.Int
+}
+
+func (m tallyMismatch) String() string {
+	return fmt.Sprintf("%s differs by %s (computed %s, from prop %s)",
+		m.Option, m.Computed.Sub(m.FromProp), m.Computed, m.FromProp)
+}
+
+// verifyTally returns the vote options where the computed tally and the prop
+// final tally differ by more than tolerance.
+func verifyTally(res tallyResult, prop govtypes.Proposal, tolerance sdk.Int) []tallyMismatch {
+	var (
+		computed   = govtypes.NewTallyResultFromMap(res.Results)
+		mismatches []tallyMismatch
 	)
-	appendTable("diff", diff)
+	for _, o := range []struct {
+		option             govtypes.VoteOption
+		computed, fromProp sdk.Int
+	}{
+		{govtypes.OptionYes, computed.Yes, prop.FinalTallyResult.Yes},
+		{govtypes.OptionNo, computed.No, prop.FinalTallyResult.No},
+		{govtypes.OptionNoWithVeto, computed.NoWithVeto, prop.FinalTallyResult.NoWithVeto},
+		{govtypes.OptionAbstain, computed.Abstain, prop.FinalTallyResult.Abstain},
+	} {
+		if o.computed.Sub(o.fromProp).Abs().GT(tolerance) {
+			mismatches = append(mismatches, tallyMismatch{Option: o.option, Computed: o.computed, FromProp: o.fromProp})
+		}
+	}
+	return mismatches
+}
+
+// tallyOutcome is the verdict of the tally, as decided by x/gov Keeper.Tally.
+type tallyOutcome struct {
+	TotalBonded sdk.Int `json:"total_bonded"`
+	// Participation is the ratio of the total voting power over the total
+	// bonded tokens.
+	Participation sdk.Dec `json:"participation"`
+	QuorumReached bool    `json:"quorum_reached"`
+	Vetoed        bool    `json:"vetoed"`
+	Passes        bool    `json:"passes"`
+	BurnDeposits  bool    `json:"burn_deposits"`
+}
+
+// newTallyOutcome applies the tally params to the tally result, the same way
+// as x/gov Keeper.Tally.
+func newTallyOutcome(res tallyResult, totalBonded sdk.Int, params govtypes.TallyParams) tallyOutcome {
+	var (
+		results          = res.Results
+		totalVotingPower = res.TotalVotingPower
+	)
+	o := tallyOutcome{
+		TotalBonded:   totalBonded,
+		Participation: sdk.ZeroDec(),
+	}
+	// If there is no staked coins, the proposal fails
+	if totalBonded.IsZero() {
+		return o
+	}
+	// If there is not enough quorum of votes, the proposal fails
+	o.Participation = totalVotingPower.Quo(totalBonded.ToDec())
+	if o.Participation.LT(params.Quorum) {
+		o.BurnDeposits = true
+		return o
+	}
+	o.QuorumReached = true
+	// If no one votes (everyone abstains), proposal fails
+	if totalVotingPower.Sub(results[govtypes.OptionAbstain]).IsZero() {
+		return o
+	}
+	// If more than 1/3 of voters veto, proposal fails
+	if results[govtypes.OptionNoWithVeto].Quo(totalVotingPower).GT(params.VetoThreshold) {
+		o.Vetoed = true
+		o.BurnDeposits = true
+		return o
+	}
+	// If more than 1/2 of non-abstaining voters vote Yes, proposal passes
+	o.Passes = results[govtypes.OptionYes].Quo(totalVotingPower.Sub(results[govtypes.OptionAbstain])).
+		GT(params.Threshold)
+	return o
+}
+
+func printTallyOutcome(w io.Writer, o tallyOutcome, params govtypes.TallyParams, prop govtypes.Proposal) {
+	fmt.Fprintln(w, "--- TALLY OUTCOME ---")
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"", "Value"})
+	table.SetAutoWrapText(false)
+	table.AppendBulk([][]string{
+		{"Total bonded", human(o.TotalBonded)},
+		{"Participation", o.Participation.String()},
+		{"Quorum reached", fmt.Sprintf("%t (quorum %s)", o.QuorumReached, params.Quorum)},
+		{"Veto triggered", fmt.Sprintf("%t (threshold %s)", o.Vetoed, params.VetoThreshold)},
+		{"Passes", fmt.Sprintf("%t (threshold %s)", o.Passes, params.Threshold)},
+		{"Burn deposits", fmt.Sprint(o.BurnDeposits)},
+		{"Prop status", prop.Status.String()},
+	})
+	table.Render()
+}
+
+// validatorSorts are the sort orders of the validators breakdown, by name.
+// Ties are sorted by address.
+var validatorSorts = map[string]func(a, b validatorTally) bool{
+	"tokens":     func(a, b validatorTally) bool { return a.BondedTokens.GT(b.BondedTokens) },
+	"power":      func(a, b validatorTally) bool { return a.TotalVotingPower.GT(b.TotalVotingPower) },
+	"deductions": func(a, b validatorTally) bool { return a.DelegatorDeductions.GT(b.DelegatorDeductions) },
+	"overrides":  func(a, b validatorTally) bool { return a.OverridingDelegators > b.OverridingDelegators },
+	"address":    func(a, b validatorTally) bool { return false },
+}
+
+// sortedValidators returns the validators breakdown of the tally, sorted by
+// one of validatorSorts.
+func sortedValidators(res tallyResult, by string) ([]validatorTally, error) {
+	less, ok := validatorSorts[by]
+	if !ok {
+		return nil, fmt.Errorf("unknown validators sort %q", by)
+	}
+	vals := make([]validatorTally, 0, len(res.Validators))
+	for _, v := range res.Validators {
+		vals = append(vals, v)
+	}
+	sort.Slice(vals, func(i, j int) bool {
+		if less(vals[i], vals[j]) {
+			return true
+		}
+		if less(vals[j], vals[i]) {
+			return false
+		}
+		return vals[i].Address < vals[j].Address
+	})
+	return vals, nil
+}
+
+func printValidatorTallies(w io.Writer, vals []validatorTally) {
+	fmt.Fprintln(w, "--- VALIDATORS ---")
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{
+		"Validator", "Bonded", "Vote", "Deducted shares", "Overriding delegators",
+		"Yes", "No", "NoWithVeto", "Abstain",
+	})
+	for _, v := range vals {
+		vote := "-"
+		if len(v.Vote) > 0 {
+			vote = voteString(v.Vote)
+		}
+		table.Append([]string{
+			v.Address,
+			human(v.BondedTokens),
+			vote,
+			human(v.DelegatorDeductions.TruncateInt()),
+			h.Comma(int64(v.OverridingDelegators)),
+			human(v.VotingPower[govtypes.OptionYes].TruncateInt()),
+			human(v.VotingPower[govtypes.OptionNo].TruncateInt()),
+			human(v.VotingPower[govtypes.OptionNoWithVeto].TruncateInt()),
+			human(v.VotingPower[govtypes.OptionAbstain].TruncateInt()),
+		})
+	}
 	table.Render()
 }
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+func TestTally(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		accAddrs = createAccountAddrs(2)
+		valAddr  = createValidatorAddrs(1)[0]
+		voteYes  = govtypes.WeightedVoteOptions{{Option: govtypes.OptionYes, Weight: sdk.OneDec()}}
+		voteNo   = govtypes.WeightedVoteOptions{{Option: govtypes.OptionNo, Weight: sdk.OneDec()}}
+		// validator slashed by half, shares are worth 0.5 token
+		valsByAddr = map[string]govtypes.ValidatorGovInfo{
+			valAddr.String(): govtypes.NewValidatorGovInfo(valAddr, sdk.NewInt(500), sdk.NewDec(1000), sdk.ZeroDec(), voteYes),
+		}
+		delegsByAddr = map[string][]stakingtypes.Delegation{
+			accAddrs[0].String(): {stakingtypes.NewDelegation(accAddrs[0], valAddr, sdk.NewDec(200))},
+			accAddrs[1].String(): {stakingtypes.NewDelegation(accAddrs[1], valAddr, sdk.NewDec(800))},
+		}
+		votesByAddr = map[string]govtypes.WeightedVoteOptions{
+			accAddrs[0].String(): voteNo,
+		}
+	)
+
+	res := tally(votesByAddr, valsByAddr, delegsByAddr)
+
+	assert.Equal(sdk.NewDec(100).String(), res.Results[govtypes.OptionNo].String())
+	// validator inherits the remaining 800 shares
+	assert.Equal(sdk.NewDec(400).String(), res.Results[govtypes.OptionYes].String())
+	assert.Equal(sdk.NewDec(500).String(), res.TotalVotingPower.String())
+	valTally := res.Validators[valAddr.String()]
+	assert.Equal(sdk.NewDec(200).String(), valTally.DelegatorDeductions.String())
+	assert.Equal(1, valTally.OverridingDelegators)
+	assert.Equal(sdk.NewDec(400).String(), valTally.VotingPower[govtypes.OptionYes].String())
+	assert.Equal(sdk.NewDec(400).String(), valTally.TotalVotingPower.String())
+	// voting power of the delegators who voted
+	assert.Len(res.Voters, 1)
+	assert.Equal(sdk.NewDec(100).String(), res.Voters[accAddrs[0].String()][govtypes.OptionNo].String())
+	// arguments are left untouched
+	assert.True(valsByAddr[valAddr.String()].DelegatorDeductions.IsZero())
+	// so a second tally gives the same result
+	res2 := tally(votesByAddr, valsByAddr, delegsByAddr)
+	assert.Equal(res.Results[govtypes.OptionYes].String(), res2.Results[govtypes.OptionYes].String())
+	assert.Equal(res.TotalVotingPower.String(), res2.TotalVotingPower.String())
+}
+
+func TestNewTallyOutcome(t *testing.T) {
+	newResults := func(yes, no, noWithVeto, abstain int64) map[govtypes.VoteOption]sdk.Dec {
+		return map[govtypes.VoteOption]sdk.Dec{
+			govtypes.OptionYes:        sdk.NewDec(yes),
+			govtypes.OptionNo:         sdk.NewDec(no),
+			govtypes.OptionNoWithVeto: sdk.NewDec(noWithVeto),
+			govtypes.OptionAbstain:    sdk.NewDec(abstain),
+		}
+	}
+	tests := []struct {
+		name            string
+		results         map[govtypes.VoteOption]sdk.Dec
+		totalBonded     int64
+		expectedOutcome tallyOutcome
+	}{
+		{
+			name:        "no bonded tokens",
+			results:     newResults(0, 0, 0, 0),
+			totalBonded: 0,
+			expectedOutcome: tallyOutcome{
+				Participation: sdk.ZeroDec(),
+			},
+		},
+		{
+			name:        "quorum not reached",
+			results:     newResults(30, 0, 0, 0),
+			totalBonded: 100,
+			expectedOutcome: tallyOutcome{
+				Participation: sdk.NewDecWithPrec(3, 1),
+				BurnDeposits:  true,
+			},
+		},
+		{
+			name:        "everyone abstains",
+			results:     newResults(0, 0, 0, 50),
+			totalBonded: 100,
+			expectedOutcome: tallyOutcome{
+				Participation: sdk.NewDecWithPrec(5, 1),
+				QuorumReached: true,
+			},
+		},
+		{
+			name:        "vetoed",
+			results:     newResults(30, 0, 25, 10),
+			totalBonded: 100,
+			expectedOutcome: tallyOutcome{
+				Participation: sdk.NewDecWithPrec(65, 2),
+				QuorumReached: true,
+				Vetoed:        true,
+				BurnDeposits:  true,
+			},
+		},
+		{
+			name: "passes",
+			// yes is 30/(60-20)=0.75 of non-abstaining voters
+			results:     newResults(30, 5, 5, 20),
+			totalBonded: 100,
+			expectedOutcome: tallyOutcome{
+				Participation: sdk.NewDecWithPrec(6, 1),
+				QuorumReached: true,
+				Passes:        true,
+			},
+		},
+		{
+			name:        "fails at threshold",
+			results:     newResults(20, 20, 0, 0),
+			totalBonded: 100,
+			expectedOutcome: tallyOutcome{
+				Participation: sdk.NewDecWithPrec(4, 1),
+				QuorumReached: true,
+			},
+		},
+		{
+			name: "participation is rounded",
+			// 2/3 is rounded up like x/gov does, not truncated
+			results:     newResults(2, 0, 0, 0),
+			totalBonded: 3,
+			expectedOutcome: tallyOutcome{
+				Participation: sdk.MustNewDecFromStr("0.666666666666666667"),
+				QuorumReached: true,
+				Passes:        true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			totalVotingPower := sdk.ZeroDec()
+			for _, v := range tt.results {
+				totalVotingPower = totalVotingPower.Add(v)
+			}
+			tt.expectedOutcome.TotalBonded = sdk.NewInt(tt.totalBonded)
+
+			res := tallyResult{Results: tt.results, TotalVotingPower: totalVotingPower}
+
+			outcome := newTallyOutcome(res, sdk.NewInt(tt.totalBonded), govtypes.DefaultTallyParams())
+
+			assert.Equal(t, tt.expectedOutcome.TotalBonded.String(), outcome.TotalBonded.String())
+			assert.Equal(t, tt.expectedOutcome.Participation.String(), outcome.Participation.String())
+			assert.Equal(t, tt.expectedOutcome.QuorumReached, outcome.QuorumReached, "QuorumReached")
+			assert.Equal(t, tt.expectedOutcome.Vetoed, outcome.Vetoed, "Vetoed")
+			assert.Equal(t, tt.expectedOutcome.Passes, outcome.Passes, "Passes")
+			assert.Equal(t, tt.expectedOutcome.BurnDeposits, outcome.BurnDeposits, "BurnDeposits")
+		})
+	}
+}
+
+func TestSortedValidators(t *testing.T) {
+	res := tallyResult{
+		Validators: map[string]validatorTally{
+			"a": {Address: "a", BondedTokens: sdk.NewInt(10), DelegatorDeductions: sdk.NewDec(1), TotalVotingPower: sdk.NewDec(9), OverridingDelegators: 1},
+			"b": {Address: "b", BondedTokens: sdk.NewInt(20), DelegatorDeductions: sdk.NewDec(5), TotalVotingPower: sdk.NewDec(0), OverridingDelegators: 3},
+			"c": {Address: "c", BondedTokens: sdk.NewInt(20), DelegatorDeductions: sdk.NewDec(2), TotalVotingPower: sdk.NewDec(18), OverridingDelegators: 1},
+		},
+	}
+	tests := []struct {
+		by            string
+		expectedAddrs []string
+		expectedErr   string
+	}{
+		{by: "tokens", expectedAddrs: []string{"b", "c", "a"}},
+		{by: "power", expectedAddrs: []string{"c", "a", "b"}},
+		{by: "deductions", expectedAddrs: []string{"b", "c", "a"}},
+		{by: "overrides", expectedAddrs: []string{"b", "a", "c"}},
+		{by: "address", expectedAddrs: []string{"a", "b", "c"}},
+		{by: "moniker", expectedErr: `unknown validators sort "moniker"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			vals, err := sortedValidators(res, tt.by)
+
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			var addrs []string
+			for _, v := range vals {
+				addrs = append(addrs, v.Address)
+			}
+			assert.Equal(t, tt.expectedAddrs, addrs)
+		})
+	}
+
+	// validators_tally.json keys are snake case like the other reports
+	bz, err := json.Marshal(res.Validators["a"])
+	require.NoError(t, err)
+	assert.Contains(t, string(bz), `"bonded_tokens":"10"`)
+	assert.Contains(t, string(bz), `"overriding_delegators":1`)
+}
+
+func TestVerifyTally(t *testing.T) {
+	res := tallyResult{
+		Results: map[govtypes.VoteOption]sdk.Dec{
+			govtypes.OptionYes:        sdk.NewDec(1000),
+			govtypes.OptionNo:         sdk.NewDec(500),
+			govtypes.OptionNoWithVeto: sdk.ZeroDec(),
+			govtypes.OptionAbstain:    sdk.NewDec(100),
+		},
+	}
+	prop := govtypes.Proposal{
+		FinalTallyResult: govtypes.NewTallyResult(sdk.NewInt(1000), sdk.NewInt(90), sdk.NewInt(510), sdk.ZeroInt()),
+	}
+	tests := []struct {
+		name               string
+		tolerance          int64
+		expectedMismatches []string
+	}{
+		{
+			name:      "no tolerance",
+			tolerance: 0,
+			expectedMismatches: []string{
+				"VOTE_OPTION_NO differs by -10 (computed 500, from prop 510)",
+				"VOTE_OPTION_ABSTAIN differs by 10 (computed 100, from prop 90)",
+			},
+		},
+		{
+			name:      "within tolerance",
+			tolerance: 10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mismatches := verifyTally(res, prop, sdk.NewInt(tt.tolerance))
+
+			var strs []string
+			for _, m := range mismatches {
+				strs = append(strs, m.String())
+			}
+			assert.Equal(t, tt.expectedMismatches, strs)
+		})
+	}
+}
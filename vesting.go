@@ -0,0 +1,46 @@
+package main
+
+import (
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	vestexported "github.com/cosmos/cosmos-sdk/x/auth/vesting/exported"
+	vestingtypes "github.com/cosmos/cosmos-sdk/x/auth/vesting/types"
+)
+
+// Vesting is the vesting schedule of a vesting account, along with its vested
+// and still vesting coins at the snapshot time.
+type Vesting struct {
+	OriginalVesting  sdk.Coins
+	DelegatedFree    sdk.Coins
+	DelegatedVesting sdk.Coins
+	// StartTime and EndTime are unix timestamps, StartTime is 0 for delayed
+	// and permanent locked vesting accounts.
+	StartTime int64
+	EndTime   int64
+	// Periods is only set for periodic vesting accounts.
+	Periods      vestingtypes.Periods `json:",omitempty"`
+	VestedCoins  sdk.Coins
+	VestingCoins sdk.Coins
+	// LockedCoins are the vesting coins that are not delegated, which are part
+	// of the account balance.
+	LockedCoins sdk.Coins
+}
+
+// newVesting returns the vesting schedule of acc at the snapshot time.
+func newVesting(acc vestexported.VestingAccount, snapshotTime time.Time) *Vesting {
+	v := &Vesting{
+		OriginalVesting:  acc.GetOriginalVesting(),
+		DelegatedFree:    acc.GetDelegatedFree(),
+		DelegatedVesting: acc.GetDelegatedVesting(),
+		StartTime:        acc.GetStartTime(),
+		EndTime:          acc.GetEndTime(),
+		VestedCoins:      acc.GetVestedCoins(snapshotTime),
+		VestingCoins:     acc.GetVestingCoins(snapshotTime),
+		LockedCoins:      acc.LockedCoins(snapshotTime),
+	}
+	if pva, ok := acc.(*vestingtypes.PeriodicVestingAccount); ok {
+		v.Periods = pva.VestingPeriods
+	}
+	return v
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	vestexported "github.com/cosmos/cosmos-sdk/x/auth/vesting/exported"
+	vestingtypes "github.com/cosmos/cosmos-sdk/x/auth/vesting/types"
+)
+
+func TestNewVesting(t *testing.T) {
+	var (
+		addr            = createAccountAddrs(1)[0]
+		originalVesting = sdk.NewCoins(sdk.NewInt64Coin("uatom", 1000))
+		// the vesting period goes from 1000 to 2000
+		startTime = int64(1000)
+		endTime   = int64(2000)
+		periods   = vestingtypes.Periods{
+			{Length: 250, Amount: sdk.NewCoins(sdk.NewInt64Coin("uatom", 100))},
+			{Length: 250, Amount: sdk.NewCoins(sdk.NewInt64Coin("uatom", 300))},
+			{Length: 500, Amount: sdk.NewCoins(sdk.NewInt64Coin("uatom", 600))},
+		}
+		newBaseAccount = func() *authtypes.BaseAccount {
+			return authtypes.NewBaseAccountWithAddress(addr)
+		}
+		continuous = func() vestexported.VestingAccount {
+			return vestingtypes.NewContinuousVestingAccount(newBaseAccount(), originalVesting, startTime, endTime)
+		}
+		delayed = func() vestexported.VestingAccount {
+			return vestingtypes.NewDelayedVestingAccount(newBaseAccount(), originalVesting, endTime)
+		}
+		periodic = func() vestexported.VestingAccount {
+			return vestingtypes.NewPeriodicVestingAccount(newBaseAccount(), originalVesting, startTime, periods)
+		}
+		before = time.Unix(500, 0)
+		during = time.Unix(1500, 0)
+		after  = time.Unix(2500, 0)
+	)
+	tests := []struct {
+		name              string
+		acc               func() vestexported.VestingAccount
+		snapshotTime      time.Time
+		expectedStartTime int64
+		expectedVested    string
+		expectedVesting   string
+		expectedLocked    string
+	}{
+		{
+			name:              "continuous before",
+			acc:               continuous,
+			snapshotTime:      before,
+			expectedStartTime: startTime,
+			expectedVested:    "",
+			expectedVesting:   "1000uatom",
+			expectedLocked:    "1000uatom",
+		},
+		{
+			name:              "continuous during",
+			acc:               continuous,
+			snapshotTime:      during,
+			expectedStartTime: startTime,
+			expectedVested:    "500uatom",
+			expectedVesting:   "500uatom",
+			expectedLocked:    "500uatom",
+		},
+		{
+			name:              "continuous after",
+			acc:               continuous,
+			snapshotTime:      after,
+			expectedStartTime: startTime,
+			expectedVested:    "1000uatom",
+			expectedVesting:   "",
+			expectedLocked:    "",
+		},
+		{
+			name: "continuous during with delegated vesting coins",
+			acc: func() vestexported.VestingAccount {
+				acc := continuous()
+				acc.TrackDelegation(before, originalVesting, sdk.NewCoins(sdk.NewInt64Coin("uatom", 300)))
+				return acc
+			},
+			snapshotTime:      during,
+			expectedStartTime: startTime,
+			expectedVested:    "500uatom",
+			expectedVesting:   "500uatom",
+			// delegated vesting coins are not in the balance
+			expectedLocked: "200uatom",
+		},
+		{
+			name:            "delayed before",
+			acc:             delayed,
+			snapshotTime:    before,
+			expectedVested:  "",
+			expectedVesting: "1000uatom",
+			expectedLocked:  "1000uatom",
+		},
+		{
+			name:            "delayed during",
+			acc:             delayed,
+			snapshotTime:    during,
+			expectedVested:  "",
+			expectedVesting: "1000uatom",
+			expectedLocked:  "1000uatom",
+		},
+		{
+			name:            "delayed after",
+			acc:             delayed,
+			snapshotTime:    after,
+			expectedVested:  "1000uatom",
+			expectedVesting: "",
+			expectedLocked:  "",
+		},
+		{
+			name:              "periodic before",
+			acc:               periodic,
+			snapshotTime:      before,
+			expectedStartTime: startTime,
+			expectedVested:    "",
+			expectedVesting:   "1000uatom",
+			expectedLocked:    "1000uatom",
+		},
+		{
+			name:              "periodic during",
+			acc:               periodic,
+			snapshotTime:      during,
+			expectedStartTime: startTime,
+			// the first 2 periods have elapsed
+			expectedVested:  "400uatom",
+			expectedVesting: "600uatom",
+			expectedLocked:  "600uatom",
+		},
+		{
+			name:              "periodic after",
+			acc:               periodic,
+			snapshotTime:      after,
+			expectedStartTime: startTime,
+			expectedVested:    "1000uatom",
+			expectedVesting:   "",
+			expectedLocked:    "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			acc := tt.acc()
+
+			v := newVesting(acc, tt.snapshotTime)
+
+			assert.Equal(originalVesting.String(), v.OriginalVesting.String())
+			assert.Equal(tt.expectedStartTime, v.StartTime)
+			assert.Equal(endTime, v.EndTime)
+			assert.Equal(tt.expectedVested, v.VestedCoins.String())
+			assert.Equal(tt.expectedVesting, v.VestingCoins.String())
+			assert.Equal(tt.expectedLocked, v.LockedCoins.String())
+			if _, ok := acc.(*vestingtypes.PeriodicVestingAccount); ok {
+				assert.Equal(periods, v.Periods)
+			} else {
+				assert.Empty(v.Periods)
+			}
+		})
+	}
+}
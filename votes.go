@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+)
+
+// voteSource is a JSON array of votes.
+type voteSource struct {
+	name string
+	open func() (*json.Decoder, io.Closer, error)
+}
+
+// sourcedVote is a vote with the name of the source it was read from.
+type sourcedVote struct {
+	govtypes.Vote
+	Source string
+	// raw is the vote as found in the source.
+	raw json.RawMessage
+}
+
+// voteOverride reports a vote replaced by a subsequent vote of the same voter.
+type voteOverride struct {
+	Previous sourcedVote
+	Vote     sourcedVote
+}
+
+func (o voteOverride) String() string {
+	return fmt.Sprintf("%s voted %s in %s, overridden by %s in %s", o.Vote.Voter,
+		voteString(o.Previous.Options), o.Previous.Source,
+		voteString(o.Vote.Options), o.Vote.Source)
+}
+
+// voteSources returns the sources of votes, ordered by height: first the votes
+// stored in the state of the block preceding the tally, then the votes
+// broadcasted in the tally block.
+func (s dataSource) voteSources() []voteSource {
+	var sources []voteSource
+	if s.fromExport() {
+		sources = append(sources,
+			voteSource{
+				name: s.preTallyExport,
+				open: func() (*json.Decoder, io.Closer, error) {
+					return openExport(s.preTallyExport, exportPaths["votes.json"].keys...)
+				},
+			},
+			// Votes are removed from the state during the tally, but read them
+			// anyway in case the tally hasn't happened yet.
+			voteSource{
+				name: s.tallyExport,
+				open: func() (*json.Decoder, io.Closer, error) {
+					return openExport(s.tallyExport, exportPaths["votes.json"].keys...)
+				},
+			},
+		)
+	} else {
+		sources = append(sources, voteSource{
+			name: "votes.json",
+			open: func() (*json.Decoder, io.Closer, error) { return s.open("votes.json") },
+		})
+		if s.has("tally_votes.json") {
+			sources = append(sources, voteSource{
+				name: "tally_votes.json",
+				open: func() (*json.Decoder, io.Closer, error) { return s.open("tally_votes.json") },
+			})
+		}
+	}
+	if s.tallyVotes != "" {
+		sources = append(sources, voteSource{
+			name: s.tallyVotes,
+			open: func() (*json.Decoder, io.Closer, error) {
+				f, err := openFile(s.tallyVotes)
+				if err != nil {
+					return nil, nil, err
+				}
+				return json.NewDecoder(f), f, nil
+			},
+		})
+	}
+	return sources
+}
+
+// voteKey identifies the vote of a voter on a proposal.
+type voteKey struct {
+	propID uint64
+	voter  string
+}
+
+// mergeVotes reads the votes of the proposal from the sources, in order. When
+// a voter has voted more than once on a proposal, only the last vote is kept,
+// at the place of the first one, and the replaced vote is reported in the
+// returned overrides if its options differ. Proposals still in voting period
+// at the tally height have the same votes in both exports, which are not
+// overrides. If propID is 0, votes are not filtered.
+func mergeVotes(sources []voteSource, propID uint64) ([]sourcedVote, []voteOverride, error) {
+	var (
+		votes        []sourcedVote
+		overrides    []voteOverride
+		voteIdxByKey = make(map[voteKey]int)
+	)
+	for _, source := range sources {
+		dec, f, err := source.open()
+		if err != nil {
+			return nil, nil, err
+		}
+		err = forEachRaw(dec, func(raw json.RawMessage) error {
+			vote := sourcedVote{Source: source.name, raw: raw}
+			if err := unmarshaler.Unmarshal(bytes.NewReader(raw), &vote.Vote); err != nil {
+				return err
+			}
+			if propID != 0 && vote.ProposalId != propID {
+				// Vote for an other proposal, only happens with exports
+				return nil
+			}
+			key := voteKey{propID: vote.ProposalId, voter: vote.Voter}
+			if i, ok := voteIdxByKey[key]; ok {
+				if !sameVoteOptions(votes[i].Options, vote.Options) {
+					overrides = append(overrides, voteOverride{Previous: votes[i], Vote: vote})
+				}
+				votes[i] = vote
+				return nil
+			}
+			voteIdxByKey[key] = len(votes)
+			votes = append(votes, vote)
+			return nil
+		})
+		f.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", filepath.Base(source.name), err)
+		}
+	}
+	return votes, overrides, nil
+}
+
+// sameVoteOptions returns true if a and b have the same options and weights,
+// in the same order.
+func sameVoteOptions(a, b govtypes.WeightedVoteOptions) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Option != b[i].Option || !a[i].Weight.Equal(b[i].Weight) {
+			return false
+		}
+	}
+	return true
+}
+
+// voteString returns a compact representation of the vote options.
+func voteString(vote govtypes.WeightedVoteOptions) string {
+	if len(vote) == 1 && vote[0].Weight.Equal(sdk.OneDec()) {
+		return vote[0].Option.String()
+	}
+	options := make([]string, len(vote))
+	for i, o := range vote {
+		options[i] = fmt.Sprintf("%s:%s", o.Option, o.Weight)
+	}
+	return strings.Join(options, ",")
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeVotes(t *testing.T) {
+	var (
+		newSource = func(name string, votes ...string) voteSource {
+			return voteSource{
+				name: name,
+				open: func() (*json.Decoder, io.Closer, error) {
+					r := io.NopCloser(strings.NewReader("[" + strings.Join(votes, ",") + "]"))
+					return json.NewDecoder(r), r, nil
+				},
+			}
+		}
+		newVote = func(propID, voter, option string) string {
+			return `{"proposal_id":"` + propID + `","voter":"` + voter + `","options":[{"option":"` +
+				option + `","weight":"1.000000000000000000"}]}`
+		}
+		voteYes     = "VOTE_OPTION_YES"
+		voteNo      = "VOTE_OPTION_NO"
+		voteAbstain = "VOTE_OPTION_ABSTAIN"
+	)
+	tests := []struct {
+		name              string
+		sources           []voteSource
+		propID            uint64
+		expectedVotes     []string
+		expectedOverrides []string
+	}{
+		{
+			name: "no duplicates",
+			sources: []voteSource{
+				newSource("pre", newVote("1", "a", voteYes), newVote("1", "b", voteNo)),
+				newSource("tally", newVote("1", "c", voteAbstain)),
+			},
+			propID:        1,
+			expectedVotes: []string{"a VOTE_OPTION_YES pre", "b VOTE_OPTION_NO pre", "c VOTE_OPTION_ABSTAIN tally"},
+		},
+		{
+			name: "filter proposal",
+			sources: []voteSource{
+				newSource("pre", newVote("1", "a", voteYes), newVote("2", "b", voteNo)),
+			},
+			propID:        1,
+			expectedVotes: []string{"a VOTE_OPTION_YES pre"},
+		},
+		{
+			name: "no filter",
+			sources: []voteSource{
+				newSource("pre", newVote("1", "a", voteYes), newVote("2", "b", voteNo)),
+			},
+			expectedVotes: []string{"a VOTE_OPTION_YES pre", "b VOTE_OPTION_NO pre"},
+		},
+		{
+			name: "no filter same voter",
+			sources: []voteSource{
+				newSource("pre", newVote("1", "a", voteYes), newVote("2", "a", voteNo)),
+			},
+			expectedVotes: []string{"a VOTE_OPTION_YES pre", "a VOTE_OPTION_NO pre"},
+		},
+		{
+			name: "last vote wins",
+			sources: []voteSource{
+				newSource("pre", newVote("1", "a", voteYes), newVote("1", "b", voteNo)),
+				newSource("tally", newVote("1", "a", voteNo), newVote("1", "a", voteAbstain)),
+			},
+			propID:        1,
+			expectedVotes: []string{"a VOTE_OPTION_ABSTAIN tally", "b VOTE_OPTION_NO pre"},
+			expectedOverrides: []string{
+				"a voted VOTE_OPTION_YES in pre, overridden by VOTE_OPTION_NO in tally",
+				"a voted VOTE_OPTION_NO in tally, overridden by VOTE_OPTION_ABSTAIN in tally",
+			},
+		},
+		{
+			name: "same vote in both exports",
+			sources: []voteSource{
+				newSource("pre", newVote("1", "a", voteYes)),
+				newSource("tally", newVote("1", "a", voteYes)),
+			},
+			propID:        1,
+			expectedVotes: []string{"a VOTE_OPTION_YES tally"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			votes, overrides, err := mergeVotes(tt.sources, tt.propID)
+
+			require.NoError(t, err)
+			var vs []string
+			for _, v := range votes {
+				vs = append(vs, v.Voter+" "+voteString(v.Options)+" "+v.Source)
+			}
+			assert.Equal(t, tt.expectedVotes, vs)
+			var ovs []string
+			for _, o := range overrides {
+				ovs = append(ovs, o.String())
+			}
+			assert.Equal(t, tt.expectedOverrides, ovs)
+		})
+	}
+}